@@ -0,0 +1,390 @@
+// Package builder composes Timeplus streaming SQL, such as window functions, emit policies,
+// historical table reads and seek_to settings, into strings that can be passed to
+// TimeplusClient.QueryStream or used as the query of a view.
+package builder
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/timeplus-io/go-client/timeplus"
+)
+
+const (
+	SeekToEarliest = "earliest"
+	SeekToLatest   = "latest"
+)
+
+var settingKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Source is what a query reads from, a stream, a window over a stream, a historical table or a subquery
+type Source struct {
+	sql string
+	err error
+}
+
+// EmitPolicy controls when a streaming query emits its results
+type EmitPolicy struct {
+	clause string
+	err    error
+}
+
+type setting struct {
+	key   string
+	value any
+}
+
+type Query struct {
+	columns  []string
+	source   *Source
+	where    []string
+	groupBy  []string
+	having   []string
+	emit     *EmitPolicy
+	orderBy  []string
+	limit    int
+	settings []setting
+	err      error
+}
+
+func Select(columns ...string) *Query {
+	return &Query{
+		columns: columns,
+	}
+}
+
+// Stream reads a stream in streaming mode, the name is quoted so a qualified name such as db.stream
+// is quoted part by part
+func Stream(name string) Source {
+	return Source{sql: quoteName(name)}
+}
+
+// Table reads the historical data of a stream, the query ends once all data is read
+func Table(name string) Source {
+	return Source{sql: fmt.Sprintf("table(%s)", quoteName(name))}
+}
+
+// Subquery reads from the result of another query
+func Subquery(q *Query, alias string) Source {
+	sql, err := q.Build()
+	if err != nil {
+		return Source{err: err}
+	}
+	if len(alias) == 0 {
+		return Source{sql: fmt.Sprintf("(%s)", sql)}
+	}
+	return Source{sql: fmt.Sprintf("(%s) AS %s", sql, timeplus.QuoteIdentifier(alias))}
+}
+
+// Tumble splits the stream into fixed size, non overlapping windows
+func Tumble(stream string, size time.Duration) Source {
+	return window("tumble", stream, "", size)
+}
+
+// TumbleBy is Tumble using timeColumn instead of _tp_time as the event time
+func TumbleBy(stream string, timeColumn string, size time.Duration) Source {
+	return window("tumble", stream, timeColumn, size)
+}
+
+// Hop splits the stream into fixed size windows which advance by step and may overlap
+func Hop(stream string, step time.Duration, size time.Duration) Source {
+	return window("hop", stream, "", step, size)
+}
+
+// HopBy is Hop using timeColumn instead of _tp_time as the event time
+func HopBy(stream string, timeColumn string, step time.Duration, size time.Duration) Source {
+	return window("hop", stream, timeColumn, step, size)
+}
+
+// Session groups events into windows which close after timeout without new events
+func Session(stream string, timeout time.Duration) Source {
+	return window("session", stream, "", timeout)
+}
+
+// SessionBy is Session using timeColumn as the event time and capping each session at maxLength,
+// a zero maxLength leaves the server default
+func SessionBy(stream string, timeColumn string, timeout time.Duration, maxLength time.Duration) Source {
+	if maxLength == 0 {
+		return window("session", stream, timeColumn, timeout)
+	}
+	return window("session", stream, timeColumn, timeout, maxLength)
+}
+
+func window(fn string, stream string, timeColumn string, intervals ...time.Duration) Source {
+	args := []string{quoteName(stream)}
+	if len(timeColumn) > 0 {
+		args = append(args, timeplus.QuoteIdentifier(timeColumn))
+	}
+	for _, d := range intervals {
+		interval, err := Interval(d)
+		if err != nil {
+			return Source{err: fmt.Errorf("invalid %s window: %w", fn, err)}
+		}
+		args = append(args, interval)
+	}
+	return Source{sql: fmt.Sprintf("%s(%s)", fn, strings.Join(args, ", "))}
+}
+
+// Interval renders d as a Timeplus interval literal such as 5s or 100ms, using the largest exact unit
+func Interval(d time.Duration) (string, error) {
+	if d <= 0 {
+		return "", fmt.Errorf("interval has to be positive, got %s", d)
+	}
+
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+		{"ms", time.Millisecond},
+		{"us", time.Microsecond},
+		{"ns", time.Nanosecond},
+	}
+	for _, unit := range units {
+		if d%unit.size == 0 {
+			return fmt.Sprintf("%d%s", d/unit.size, unit.suffix), nil
+		}
+	}
+	return "", fmt.Errorf("unsupported interval %s", d)
+}
+
+// EmitPeriodic emits the aggregation result every interval
+func EmitPeriodic(interval time.Duration) EmitPolicy {
+	return emitWithInterval("EMIT PERIODIC %s", interval)
+}
+
+// EmitAfterWatermark emits the result of a window once the watermark passes its end
+func EmitAfterWatermark() EmitPolicy {
+	return EmitPolicy{clause: "EMIT AFTER WATERMARK"}
+}
+
+// EmitAfterWatermarkWithDelay waits an extra delay after the watermark to tolerate late events
+func EmitAfterWatermarkWithDelay(delay time.Duration) EmitPolicy {
+	return emitWithInterval("EMIT AFTER WATERMARK AND DELAY %s", delay)
+}
+
+// EmitLast only aggregates the events of the last interval
+func EmitLast(interval time.Duration) EmitPolicy {
+	return emitWithInterval("EMIT LAST %s", interval)
+}
+
+// EmitOnUpdate emits the result whenever it changes
+func EmitOnUpdate() EmitPolicy {
+	return EmitPolicy{clause: "EMIT ON UPDATE"}
+}
+
+func emitWithInterval(format string, d time.Duration) EmitPolicy {
+	interval, err := Interval(d)
+	if err != nil {
+		return EmitPolicy{err: fmt.Errorf("invalid emit policy: %w", err)}
+	}
+	return EmitPolicy{clause: fmt.Sprintf(format, interval)}
+}
+
+// Latest returns the latest value of column
+func Latest(column string) string {
+	return fmt.Sprintf("latest(%s)", column)
+}
+
+// Earliest returns the earliest value of column
+func Earliest(column string) string {
+	return fmt.Sprintf("earliest(%s)", column)
+}
+
+func As(expr string, alias string) string {
+	return fmt.Sprintf("%s AS %s", expr, alias)
+}
+
+func (q *Query) From(source Source) *Query {
+	if source.err != nil {
+		q.setErr(source.err)
+	}
+	q.source = &source
+	return q
+}
+
+// Where adds a condition, multiple conditions are combined with AND.
+// args are bound to the placeholders of cond with timeplus.Bind.
+func (q *Query) Where(cond string, args ...any) *Query {
+	if bound := q.bind(cond, args); len(bound) > 0 {
+		q.where = append(q.where, bound)
+	}
+	return q
+}
+
+func (q *Query) GroupBy(columns ...string) *Query {
+	q.groupBy = append(q.groupBy, columns...)
+	return q
+}
+
+// Having adds a condition on the aggregation result, multiple conditions are combined with AND
+func (q *Query) Having(cond string, args ...any) *Query {
+	if bound := q.bind(cond, args); len(bound) > 0 {
+		q.having = append(q.having, bound)
+	}
+	return q
+}
+
+func (q *Query) Emit(policy EmitPolicy) *Query {
+	if policy.err != nil {
+		q.setErr(policy.err)
+	}
+	q.emit = &policy
+	return q
+}
+
+func (q *Query) OrderBy(columns ...string) *Query {
+	q.orderBy = append(q.orderBy, columns...)
+	return q
+}
+
+func (q *Query) Limit(limit int) *Query {
+	q.limit = limit
+	return q
+}
+
+// Setting adds a query setting, the value is escaped as a literal and the key has to be a plain name
+func (q *Query) Setting(key string, value any) *Query {
+	if !settingKeyPattern.MatchString(key) {
+		q.setErr(fmt.Errorf("invalid setting name %q", key))
+		return q
+	}
+	for i := range q.settings {
+		if q.settings[i].key == key {
+			q.settings[i].value = value
+			return q
+		}
+	}
+	q.settings = append(q.settings, setting{key: key, value: value})
+	return q
+}
+
+// SeekTo starts a streaming query from position, which is SeekToEarliest, SeekToLatest,
+// a timestamp string or a relative time such as -1h
+func (q *Query) SeekTo(position string) *Query {
+	return q.Setting("seek_to", position)
+}
+
+// SeekToTime starts a streaming query from t
+func (q *Query) SeekToTime(t time.Time) *Query {
+	return q.Setting("seek_to", t.UTC().Format("2006-01-02 15:04:05.000000000"))
+}
+
+func (q *Query) bind(cond string, args []any) string {
+	if len(args) == 0 {
+		return cond
+	}
+	bound, err := timeplus.Bind(cond, args...)
+	if err != nil {
+		q.setErr(fmt.Errorf("invalid condition %q: %w", cond, err))
+		return ""
+	}
+	return bound
+}
+
+func (q *Query) setErr(err error) {
+	if q.err == nil {
+		q.err = err
+	}
+}
+
+func (q *Query) Build() (string, error) {
+	if q.err != nil {
+		return "", q.err
+	}
+	if q.source == nil {
+		return "", fmt.Errorf("query has no source")
+	}
+
+	columns := q.columns
+	if len(columns) == 0 {
+		columns = []string{"*"}
+	}
+
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	b.WriteString(strings.Join(columns, ", "))
+	b.WriteString(" FROM ")
+	b.WriteString(q.source.sql)
+
+	if len(q.where) > 0 {
+		b.WriteString(" WHERE ")
+		b.WriteString(joinConditions(q.where))
+	}
+	if len(q.groupBy) > 0 {
+		b.WriteString(" GROUP BY ")
+		b.WriteString(strings.Join(q.groupBy, ", "))
+	}
+	if len(q.having) > 0 {
+		b.WriteString(" HAVING ")
+		b.WriteString(joinConditions(q.having))
+	}
+	if q.emit != nil {
+		b.WriteString(" ")
+		b.WriteString(q.emit.clause)
+	}
+	if len(q.orderBy) > 0 {
+		b.WriteString(" ORDER BY ")
+		b.WriteString(strings.Join(q.orderBy, ", "))
+	}
+	if q.limit > 0 {
+		fmt.Fprintf(&b, " LIMIT %d", q.limit)
+	}
+	if len(q.settings) > 0 {
+		settings := make([]string, len(q.settings))
+		for i, s := range q.settings {
+			value, err := timeplus.Escape(s.value)
+			if err != nil {
+				return "", fmt.Errorf("invalid setting %s: %w", s.key, err)
+			}
+			settings[i] = fmt.Sprintf("%s=%s", s.key, value)
+		}
+		b.WriteString(" SETTINGS ")
+		b.WriteString(strings.Join(settings, ", "))
+	}
+
+	return b.String(), nil
+}
+
+// View returns the definition of a view running this query, ready for TimeplusClient.CreateView
+func (q *Query) View(name string, materialized bool) (timeplus.View, error) {
+	sql, err := q.Build()
+	if err != nil {
+		return timeplus.View{}, err
+	}
+	return timeplus.View{
+		Name:         name,
+		Query:        sql,
+		Materialized: materialized,
+	}, nil
+}
+
+// quoteName quotes every part of a name which may be qualified by its database
+func quoteName(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = timeplus.QuoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func joinConditions(conds []string) string {
+	if len(conds) == 1 {
+		return conds[0]
+	}
+	wrapped := make([]string, len(conds))
+	for i, cond := range conds {
+		wrapped[i] = "(" + cond + ")"
+	}
+	return strings.Join(wrapped, " AND ")
+}
+
+// Named is timeplus.Named, re-exported for use with Where and Having
+func Named(name string, value any) timeplus.NamedArg {
+	return timeplus.Named(name, value)
+}
@@ -0,0 +1,111 @@
+package builder_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timeplus-io/go-client/builder"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestGoldenSQL(t *testing.T) {
+	cases := map[string]*builder.Query{
+		"stream_filter": builder.Select("cid", "speed_kmh").
+			From(builder.Stream("car_live_data")).
+			Where("cid = ?", "c'0001").
+			Where("speed_kmh > @speed", builder.Named("speed", 80)),
+		"tumble_emit_periodic": builder.Select("window_start", "cid", builder.As("avg(speed_kmh)", "speed")).
+			From(builder.Tumble("car_live_data", 5*time.Second)).
+			GroupBy("window_start", "cid").
+			Emit(builder.EmitPeriodic(time.Second)),
+		"hop_watermark_delay": builder.Select("window_start", "count()").
+			From(builder.HopBy("car_live_data", "time", 2*time.Second, time.Minute)).
+			GroupBy("window_start").
+			Emit(builder.EmitAfterWatermarkWithDelay(500 * time.Millisecond)),
+		"session_having": builder.Select("window_start", "window_end", "cid", "count() AS cnt").
+			From(builder.SessionBy("car_live_data", "time", 5*time.Minute, 2*time.Hour)).
+			GroupBy("window_start", "window_end", "cid").
+			Having("cnt > ?", 10),
+		"table_latest": builder.Select("cid", builder.Latest("speed_kmh"), builder.Earliest("gas_percent")).
+			From(builder.Table("car_live_data")).
+			GroupBy("cid").
+			OrderBy("cid").
+			Limit(10),
+		"emit_last_seek_to": builder.Select("cid", "max(speed_kmh)").
+			From(builder.Stream("car_live_data")).
+			GroupBy("cid").
+			Emit(builder.EmitLast(time.Hour)).
+			SeekTo(builder.SeekToEarliest),
+		"subquery_seek_to_time": builder.Select().
+			From(builder.Subquery(builder.Select("cid", "speed_kmh").From(builder.Stream("car_live_data")), "cars")).
+			Where("speed_kmh > 0").
+			SeekToTime(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)).
+			Setting("query_mode", "streaming"),
+	}
+
+	for name, q := range cases {
+		sql, err := q.Build()
+		if err != nil {
+			t.Fatalf("%s: failed to build sql: %s", name, err)
+		}
+
+		golden := filepath.Join("testdata", name+".golden")
+		if *update {
+			if err := os.WriteFile(golden, []byte(sql+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		expected, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("%s: failed to read golden file: %s", name, err)
+		}
+		if sql != strings.TrimSuffix(string(expected), "\n") {
+			t.Errorf("%s: sql mismatch\nexpected: %s\n     got: %s", name, expected, sql)
+		}
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	if _, err := builder.Select("a").Build(); err == nil {
+		t.Errorf("expected error without source")
+	}
+	if _, err := builder.Select("a").From(builder.Tumble("s", 0)).Build(); err == nil {
+		t.Errorf("expected error for empty window")
+	}
+	if _, err := builder.Select("a").From(builder.Stream("s")).Emit(builder.EmitPeriodic(-time.Second)).Build(); err == nil {
+		t.Errorf("expected error for negative emit interval")
+	}
+	if _, err := builder.Select("a").From(builder.Stream("s")).Where("a = ? and b = ?", 1).Build(); err == nil {
+		t.Errorf("expected error for missing parameter")
+	}
+	if _, err := builder.Select("a").From(builder.Stream("s")).Setting("seek_to='latest', x", 1).Build(); err == nil {
+		t.Errorf("expected error for invalid setting name")
+	}
+}
+
+func TestQuotedNames(t *testing.T) {
+	sql, err := builder.Select("a").From(builder.TumbleBy("db.s`; drop stream s; --", "time", time.Second)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != "SELECT a FROM tumble(`db`.`s\\`; drop stream s; --`, `time`, 1s)" {
+		t.Errorf("unexpected sql %s", sql)
+	}
+}
+
+func TestView(t *testing.T) {
+	view, err := builder.Select("cid").From(builder.Stream("car_live_data")).View("cars", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.Name != "cars" || !view.Materialized || view.Query != "SELECT cid FROM `car_live_data`" {
+		t.Errorf("unexpected view %+v", view)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"time"
@@ -33,5 +34,9 @@ func main() {
 	m.Observe("timeplus", "x1", []any{"xxx", "xxx", "xxx"}, []any{0}, nil)
 	m.Observe("timeplus", "x1", []any{"xxx", "xxx", "xxx"}, []any{nil}, nil)
 
-	time.Sleep(3 * time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := m.Close(ctx); err != nil {
+		fmt.Printf("failed to close metric, %s\n", err)
+	}
 }
@@ -0,0 +1,445 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+var DefaultObjectives = []float64{0.5, 0.9, 0.99}
+
+// Extra tags describing the rows flushed by histograms and summaries
+const (
+	BucketTag   = "le"
+	QuantileTag = "quantile"
+	StatTag     = "stat"
+	StatSum     = "sum"
+	StatCount   = "count"
+)
+
+// instrument aggregates observations in process and turns them into rows on every flush
+type instrument interface {
+	collect(timestamp time.Time) []*Observation
+}
+
+// series is one combination of namespace, subsystem and tags of an instrument
+type series struct {
+	namespace string
+	subsystem string
+	tags      []any
+	updated   bool
+}
+
+type seriesSet[T any] struct {
+	metrics    *Metrics
+	valueIndex int
+	lock       sync.Mutex
+	series     map[string]*T
+	order      []string
+	// touched is the last update of every series, the idle series are dropped after a flush
+	touched     map[string]time.Time
+	idleTimeout time.Duration
+}
+
+func newSeriesSet[T any](m *Metrics, value string) (*seriesSet[T], error) {
+	index := indexOf(m.valueNames, value)
+	if index < 0 {
+		return nil, fmt.Errorf("value %s is not defined in metrics %s", value, m.name)
+	}
+	return &seriesSet[T]{
+		metrics:     m,
+		valueIndex:  index,
+		series:      make(map[string]*T),
+		touched:     make(map[string]time.Time),
+		idleTimeout: m.options.SeriesIdleTimeout,
+	}, nil
+}
+
+// get returns the series of namespace, subsystem and tags, the caller has to hold the lock
+func (s *seriesSet[T]) get(namespace string, subsystem string, tags []any, create func(series) *T) (*T, error) {
+	if s.metrics.isClosed() {
+		return nil, ErrMetricsClosed
+	}
+
+	tags, err := s.metrics.validateTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	keyBytes, err := json.Marshal([]any{namespace, subsystem, tags})
+	if err != nil {
+		return nil, fmt.Errorf("invalid tags: %w", err)
+	}
+	key := string(keyBytes)
+	s.touched[key] = time.Now()
+
+	if found, ok := s.series[key]; ok {
+		return found, nil
+	}
+
+	created := create(series{namespace: namespace, subsystem: subsystem, tags: tags})
+	s.series[key] = created
+	s.order = append(s.order, key)
+	return created, nil
+}
+
+// evictIdle drops the series not updated within the idle timeout, the caller has to hold the lock
+// and has to have collected the series
+func (s *seriesSet[T]) evictIdle(now time.Time) {
+	if s.idleTimeout <= 0 {
+		return
+	}
+	kept := s.order[:0]
+	for _, key := range s.order {
+		if now.Sub(s.touched[key]) > s.idleTimeout {
+			delete(s.series, key)
+			delete(s.touched, key)
+			continue
+		}
+		kept = append(kept, key)
+	}
+	s.order = kept
+}
+
+func (s *seriesSet[T]) observation(timestamp time.Time, sr *series, value float64, extraTags map[string]any) *Observation {
+	values := make([]any, len(s.metrics.valueNames))
+	values[s.valueIndex] = value
+	return &Observation{
+		timestamp: timestamp,
+		namespace: sr.namespace,
+		subsystem: sr.subsystem,
+		tags:      sr.tags,
+		values:    values,
+		extraTags: extraTags,
+	}
+}
+
+// Counter is a monotonically increasing value, the total is flushed for every series updated within the interval
+type Counter struct {
+	set *seriesSet[counterSeries]
+}
+
+type counterSeries struct {
+	series
+	total float64
+}
+
+// Gauge is a value which can go up and down, the latest value is flushed for every series set within the interval
+type Gauge struct {
+	set *seriesSet[gaugeSeries]
+}
+
+type gaugeSeries struct {
+	series
+	value float64
+}
+
+// Histogram counts observations in configurable buckets, for every series updated within the interval
+// the cumulative count of each bucket is flushed with the le extra tag, followed by the sum and count
+type Histogram struct {
+	set     *seriesSet[histogramSeries]
+	buckets []float64
+}
+
+type histogramSeries struct {
+	series
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// Summary calculates quantiles of the observations within each interval,
+// the quantiles are flushed with the quantile extra tag, followed by the sum and count of the interval.
+// Beyond MetricsOptions.SummaryMaxSamples observations the quantiles are estimated from a uniform sample.
+type Summary struct {
+	set        *seriesSet[summarySeries]
+	objectives []float64
+	maxSamples int
+}
+
+type summarySeries struct {
+	series
+	// samples is a reservoir of the observations of the interval
+	samples []float64
+	sum     float64
+	count   uint64
+}
+
+// NewCounter creates a counter writing into the value column named value
+func (m *Metrics) NewCounter(value string) (*Counter, error) {
+	set, err := newSeriesSet[counterSeries](m, value)
+	if err != nil {
+		return nil, err
+	}
+	c := &Counter{set: set}
+	m.register(c)
+	return c, nil
+}
+
+func (m *Metrics) NewGauge(value string) (*Gauge, error) {
+	set, err := newSeriesSet[gaugeSeries](m, value)
+	if err != nil {
+		return nil, err
+	}
+	g := &Gauge{set: set}
+	m.register(g)
+	return g, nil
+}
+
+// NewHistogram creates a histogram with the upper bounds of buckets, DefaultBuckets is used if buckets is empty
+func (m *Metrics) NewHistogram(value string, buckets []float64) (*Histogram, error) {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := make([]float64, len(buckets))
+	copy(sorted, buckets)
+	sort.Float64s(sorted)
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i] == sorted[i-1] {
+			return nil, fmt.Errorf("duplicated histogram bucket %v", sorted[i])
+		}
+	}
+
+	set, err := newSeriesSet[histogramSeries](m, value)
+	if err != nil {
+		return nil, err
+	}
+	h := &Histogram{set: set, buckets: sorted}
+	m.register(h)
+	return h, nil
+}
+
+// NewSummary creates a summary reporting objectives quantiles, DefaultObjectives is used if objectives is empty
+func (m *Metrics) NewSummary(value string, objectives []float64) (*Summary, error) {
+	if len(objectives) == 0 {
+		objectives = DefaultObjectives
+	}
+	for _, q := range objectives {
+		if q < 0 || q > 1 {
+			return nil, fmt.Errorf("invalid summary objective %v, should be within [0, 1]", q)
+		}
+	}
+
+	set, err := newSeriesSet[summarySeries](m, value)
+	if err != nil {
+		return nil, err
+	}
+	maxSamples := m.options.SummaryMaxSamples
+	if maxSamples <= 0 {
+		maxSamples = DefaultSummaryMaxSamples
+	}
+	s := &Summary{set: set, objectives: objectives, maxSamples: maxSamples}
+	m.register(s)
+	return s, nil
+}
+
+func (c *Counter) Inc(namespace string, subsystem string, tags []any) error {
+	return c.Add(namespace, subsystem, tags, 1)
+}
+
+func (c *Counter) Add(namespace string, subsystem string, tags []any, delta float64) error {
+	if delta < 0 {
+		return fmt.Errorf("counter cannot decrease, got %v", delta)
+	}
+	if err := checkFinite(delta); err != nil {
+		return err
+	}
+
+	c.set.lock.Lock()
+	defer c.set.lock.Unlock()
+
+	s, err := c.set.get(namespace, subsystem, tags, func(sr series) *counterSeries {
+		return &counterSeries{series: sr}
+	})
+	if err != nil {
+		return err
+	}
+	s.total += delta
+	s.updated = true
+	return nil
+}
+
+func (c *Counter) collect(timestamp time.Time) []*Observation {
+	c.set.lock.Lock()
+	defer c.set.lock.Unlock()
+
+	result := make([]*Observation, 0)
+	for _, key := range c.set.order {
+		s := c.set.series[key]
+		if s.updated {
+			result = append(result, c.set.observation(timestamp, &s.series, s.total, nil))
+			s.updated = false
+		}
+	}
+	c.set.evictIdle(timestamp)
+	return result
+}
+
+func (g *Gauge) Set(namespace string, subsystem string, tags []any, value float64) error {
+	return g.update(namespace, subsystem, tags, value, func(s *gaugeSeries) { s.value = value })
+}
+
+func (g *Gauge) Add(namespace string, subsystem string, tags []any, delta float64) error {
+	return g.update(namespace, subsystem, tags, delta, func(s *gaugeSeries) { s.value += delta })
+}
+
+func (g *Gauge) update(namespace string, subsystem string, tags []any, v float64, fn func(*gaugeSeries)) error {
+	if err := checkFinite(v); err != nil {
+		return err
+	}
+
+	g.set.lock.Lock()
+	defer g.set.lock.Unlock()
+
+	s, err := g.set.get(namespace, subsystem, tags, func(sr series) *gaugeSeries {
+		return &gaugeSeries{series: sr}
+	})
+	if err != nil {
+		return err
+	}
+	fn(s)
+	s.updated = true
+	return nil
+}
+
+func (g *Gauge) collect(timestamp time.Time) []*Observation {
+	g.set.lock.Lock()
+	defer g.set.lock.Unlock()
+
+	result := make([]*Observation, 0)
+	for _, key := range g.set.order {
+		s := g.set.series[key]
+		if s.updated {
+			result = append(result, g.set.observation(timestamp, &s.series, s.value, nil))
+			s.updated = false
+		}
+	}
+	g.set.evictIdle(timestamp)
+	return result
+}
+
+func (h *Histogram) Observe(namespace string, subsystem string, tags []any, value float64) error {
+	if err := checkFinite(value); err != nil {
+		return err
+	}
+
+	h.set.lock.Lock()
+	defer h.set.lock.Unlock()
+
+	s, err := h.set.get(namespace, subsystem, tags, func(sr series) *histogramSeries {
+		return &histogramSeries{series: sr, counts: make([]uint64, len(h.buckets))}
+	})
+	if err != nil {
+		return err
+	}
+
+	// the first bucket whose upper bound is not less than value
+	index := sort.SearchFloat64s(h.buckets, value)
+	if index < len(h.buckets) {
+		s.counts[index]++
+	}
+	s.sum += value
+	s.count++
+	s.updated = true
+	return nil
+}
+
+func (h *Histogram) collect(timestamp time.Time) []*Observation {
+	h.set.lock.Lock()
+	defer h.set.lock.Unlock()
+
+	result := make([]*Observation, 0)
+	for _, key := range h.set.order {
+		s := h.set.series[key]
+		if !s.updated {
+			continue
+		}
+
+		var cumulative uint64
+		for index, bound := range h.buckets {
+			cumulative += s.counts[index]
+			result = append(result, h.set.observation(timestamp, &s.series, float64(cumulative), map[string]any{BucketTag: formatBound(bound)}))
+		}
+		result = append(result, h.set.observation(timestamp, &s.series, float64(s.count), map[string]any{BucketTag: "+Inf"}))
+		result = append(result, h.set.observation(timestamp, &s.series, s.sum, map[string]any{StatTag: StatSum}))
+		result = append(result, h.set.observation(timestamp, &s.series, float64(s.count), map[string]any{StatTag: StatCount}))
+		s.updated = false
+	}
+	h.set.evictIdle(timestamp)
+	return result
+}
+
+func (s *Summary) Observe(namespace string, subsystem string, tags []any, value float64) error {
+	if err := checkFinite(value); err != nil {
+		return err
+	}
+
+	s.set.lock.Lock()
+	defer s.set.lock.Unlock()
+
+	sr, err := s.set.get(namespace, subsystem, tags, func(sr series) *summarySeries {
+		return &summarySeries{series: sr}
+	})
+	if err != nil {
+		return err
+	}
+	sr.count++
+	if len(sr.samples) < s.maxSamples {
+		sr.samples = append(sr.samples, value)
+	} else if index := rand.Int63n(int64(sr.count)); index < int64(s.maxSamples) {
+		sr.samples[index] = value
+	}
+	sr.sum += value
+	sr.updated = true
+	return nil
+}
+
+func (s *Summary) collect(timestamp time.Time) []*Observation {
+	s.set.lock.Lock()
+	defer s.set.lock.Unlock()
+
+	result := make([]*Observation, 0)
+	for _, key := range s.set.order {
+		sr := s.set.series[key]
+		if !sr.updated {
+			continue
+		}
+
+		sort.Float64s(sr.samples)
+		for _, q := range s.objectives {
+			result = append(result, s.set.observation(timestamp, &sr.series, quantile(sr.samples, q), map[string]any{QuantileTag: formatBound(q)}))
+		}
+		result = append(result, s.set.observation(timestamp, &sr.series, sr.sum, map[string]any{StatTag: StatSum}))
+		result = append(result, s.set.observation(timestamp, &sr.series, float64(sr.count), map[string]any{StatTag: StatCount}))
+
+		// a summary only covers the observations of one interval
+		sr.samples = sr.samples[:0]
+		sr.sum = 0
+		sr.count = 0
+		sr.updated = false
+	}
+	s.set.evictIdle(timestamp)
+	return result
+}
+
+// quantile uses the nearest rank of the sorted samples
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+func formatBound(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestMetrics() *Metrics {
+	m := &Metrics{
+		name:         "test",
+		tagNames:     []string{"host"},
+		observations: make([]*Observation, 0),
+	}
+	m.setValueNames([]string{"requests", "latency"})
+	return m
+}
+
+func TestInstruments(t *testing.T) {
+	m := newTestMetrics()
+
+	counter, err := m.NewCounter("requests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	histogram, err := m.NewHistogram("latency", []float64{1, 0.1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary, err := m.NewSummary("latency", []float64{0.5, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gauge, err := m.NewGauge("latency")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.NewCounter("unknown"); err == nil {
+		t.Errorf("expected error for unknown value")
+	}
+
+	for i := 0; i < 3; i++ {
+		counter.Inc("app", "http", []any{"a"})
+	}
+	counter.Add("app", "http", []any{"b"}, 2)
+	if err := counter.Add("app", "http", []any{"a"}, -1); err == nil {
+		t.Errorf("expected error for decreasing counter")
+	}
+	if err := counter.Inc("app", "http", []any{"a", "b"}); err == nil {
+		t.Errorf("expected error for wrong number of tags")
+	}
+
+	for _, v := range []float64{0.05, 0.5, 0.7, 5} {
+		histogram.Observe("app", "http", []any{"a"}, v)
+		summary.Observe("app", "http", []any{"a"}, v)
+	}
+	gauge.Set("app", "http", []any{"a"}, 3)
+	gauge.Add("app", "http", []any{"a"}, 1.5)
+
+	obs := m.getObservations()
+	// 2 counter series, 2 buckets + inf + sum + count, 2 quantiles + sum + count, 1 gauge
+	if len(obs) != 2+5+4+1 {
+		t.Fatalf("unexpected number of observations %d", len(obs))
+	}
+
+	expected := []struct {
+		tag   string
+		extra map[string]any
+		index int
+		value float64
+	}{
+		{"a", nil, 0, 3},
+		{"b", nil, 0, 2},
+		{"a", map[string]any{BucketTag: "0.1"}, 1, 1},
+		{"a", map[string]any{BucketTag: "1"}, 1, 3},
+		{"a", map[string]any{BucketTag: "+Inf"}, 1, 4},
+		{"a", map[string]any{StatTag: StatSum}, 1, 6.25},
+		{"a", map[string]any{StatTag: StatCount}, 1, 4},
+		{"a", map[string]any{QuantileTag: "0.5"}, 1, 0.5},
+		{"a", map[string]any{QuantileTag: "1"}, 1, 5},
+		{"a", map[string]any{StatTag: StatSum}, 1, 6.25},
+		{"a", map[string]any{StatTag: StatCount}, 1, 4},
+		{"a", nil, 1, 4.5},
+	}
+	for i, e := range expected {
+		ob := obs[i]
+		if ob.tags[0] != e.tag || ob.values[e.index] != e.value || ob.values[1-e.index] != nil {
+			t.Errorf("observation %d: unexpected tags %v values %v", i, ob.tags, ob.values)
+		}
+		for k, v := range e.extra {
+			if ob.extraTags[k] != v {
+				t.Errorf("observation %d: unexpected extra tags %v", i, ob.extraTags)
+			}
+		}
+	}
+
+	// only the series updated within the interval are flushed
+	counter.Inc("app", "http", []any{"a"})
+	obs = m.getObservations()
+	if len(obs) != 1 || obs[0].values[0] != float64(4) {
+		t.Errorf("unexpected observations after second interval %v", obs)
+	}
+}
+
+func TestInstrumentsBounded(t *testing.T) {
+	m := newTestMetrics()
+	m.options.SeriesIdleTimeout = time.Millisecond
+	m.options.SummaryMaxSamples = 10
+
+	counter, err := m.NewCounter("requests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary, err := m.NewSummary("latency", []float64{0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 1000; i++ {
+		summary.Observe("app", "http", []any{"a"}, float64(i))
+	}
+	counter.Inc("app", "http", []any{"idle"})
+	obs := m.getObservations()
+	if len(obs) != 1+3 || obs[3].values[1] != float64(1000) {
+		t.Fatalf("expected the exact count of the summary, got %v", obs)
+	}
+	if samples := len(summary.set.series[summary.set.order[0]].samples); samples != 0 {
+		t.Errorf("expected the samples to be reset after the flush, got %d", samples)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	m.getObservations()
+	if len(counter.set.series) != 0 || len(summary.set.series) != 0 {
+		t.Errorf("expected the idle series to be dropped, got %d and %d", len(counter.set.series), len(summary.set.series))
+	}
+
+	// a dropped counter starts again from zero
+	counter.Inc("app", "http", []any{"idle"})
+	if obs := m.getObservations(); len(obs) != 1 || obs[0].values[0] != float64(1) {
+		t.Errorf("unexpected observations after eviction %v", obs)
+	}
+}
+
+func TestSummaryReservoir(t *testing.T) {
+	m := newTestMetrics()
+	m.options.SummaryMaxSamples = 100
+	summary, err := m.NewSummary("latency", []float64{0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 10000; i++ {
+		summary.Observe("app", "http", []any{"a"}, float64(i))
+	}
+	sr := summary.set.series[summary.set.order[0]]
+	if len(sr.samples) != 100 || sr.count != 10000 {
+		t.Fatalf("expected 100 samples of 10000 observations, got %d of %d", len(sr.samples), sr.count)
+	}
+
+	obs := m.getObservations()
+	if median := obs[0].values[1].(float64); median < 3000 || median > 7000 {
+		t.Errorf("median estimate %v too far from 5000", median)
+	}
+}
@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+)
+
+// Labels names the tags of an observation, the tags which are not set are stored as nil
+type Labels map[string]string
+
+// Values names the values of an observation, the values which are not set are stored as nil
+type Values map[string]float64
+
+// BoundMetrics is a metrics with its tags resolved from labels once, so recording
+// in hot paths neither looks up nor validates the tags again
+type BoundMetrics struct {
+	metrics      *Metrics
+	namespace    string
+	subsystem    string
+	tags         []any
+	valueIndexes map[string]int
+	err          error
+}
+
+// With binds labels to the tags of the metrics. An unknown label is reported by Err and Record.
+func (m *Metrics) With(labels Labels) *BoundMetrics {
+	b := &BoundMetrics{
+		metrics:      m,
+		tags:         make([]any, len(m.tagNames)),
+		valueIndexes: m.valueIndexes,
+	}
+
+	for name, value := range labels {
+		index := indexOf(m.tagNames, name)
+		if index < 0 {
+			b.err = fmt.Errorf("label %s is not a tag of metrics %s, should be one of %v", name, m.name, m.tagNames)
+			return b
+		}
+		b.tags[index] = value
+	}
+	return b
+}
+
+// Namespace returns a copy of b recording with namespace and subsystem
+func (b *BoundMetrics) Namespace(namespace string, subsystem string) *BoundMetrics {
+	copied := *b
+	copied.namespace = namespace
+	copied.subsystem = subsystem
+	return &copied
+}
+
+func (b *BoundMetrics) Err() error {
+	return b.err
+}
+
+func (b *BoundMetrics) Record(values Values) error {
+	return b.RecordWithExtraTags(values, nil)
+}
+
+func (b *BoundMetrics) RecordWithExtraTags(values Values, extraTags map[string]interface{}) error {
+	return b.record(time.Now(), values, extraTags)
+}
+
+// RecordAt records values observed at timestamp instead of now, such as samples forwarded from another system
+func (b *BoundMetrics) RecordAt(timestamp time.Time, values Values) error {
+	return b.record(timestamp, values, nil)
+}
+
+func (b *BoundMetrics) RecordAtWithExtraTags(timestamp time.Time, values Values, extraTags map[string]interface{}) error {
+	return b.record(timestamp, values, extraTags)
+}
+
+func (b *BoundMetrics) record(timestamp time.Time, values Values, extraTags map[string]interface{}) error {
+	m := b.metrics
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if b.err != nil {
+		m.stats.recordRejected()
+		return b.err
+	}
+	if m.closed {
+		m.stats.recordRejected()
+		return ErrMetricsClosed
+	}
+
+	row := m.newRow()
+	for name, value := range values {
+		index, ok := b.valueIndexes[name]
+		if !ok {
+			m.stats.recordRejected()
+			return fmt.Errorf("%s is not a value of metrics %s, should be one of %v", name, m.name, m.valueNames)
+		}
+		if err := checkFinite(value); err != nil {
+			if m.nonFinitePolicy != NullNonFinite {
+				m.stats.recordRejected()
+				return fmt.Errorf("invalid value %s: %w", name, err)
+			}
+			continue
+		}
+		row[index] = value
+	}
+
+	if err := validateExtraTags(extraTags); err != nil {
+		m.stats.recordRejected()
+		return err
+	}
+
+	m.appendObservation(timestamp, b.namespace, b.subsystem, b.tags, row, extraTags)
+	return nil
+}
+
+func indexOf(names []string, name string) int {
+	for index, n := range names {
+		if n == name {
+			return index
+		}
+	}
+	return -1
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"testing"
+)
+
+func TestLabels(t *testing.T) {
+	m := newTestMetrics()
+	m.tagNames = []string{"host", "region"}
+
+	bound := m.With(Labels{"region": "us"}).Namespace("app", "http")
+	if err := bound.Record(Values{"latency": 0.4}); err != nil {
+		t.Fatalf("failed to record: %s", err)
+	}
+	if err := bound.RecordWithExtraTags(Values{"requests": 2, "latency": 0.1}, map[string]interface{}{"a": "b"}); err != nil {
+		t.Fatalf("failed to record: %s", err)
+	}
+
+	ob := m.observations[0]
+	if ob.namespace != "app" || ob.subsystem != "http" || ob.tags[0] != nil || ob.tags[1] != "us" {
+		t.Errorf("unexpected observation %+v", ob)
+	}
+	if ob.values[0] != nil || ob.values[1] != 0.4 {
+		t.Errorf("unexpected values %v", ob.values)
+	}
+	if ob = m.observations[1]; ob.values[0] != float64(2) || ob.extraTags["a"] != "b" {
+		t.Errorf("unexpected observation %+v", ob)
+	}
+
+	unknown := m.With(Labels{"zone": "a"})
+	if unknown.Err() == nil || unknown.Record(Values{"latency": 1}) == nil {
+		t.Errorf("expected error for unknown label")
+	}
+	if err := bound.Record(Values{"cpu": 1}); err == nil {
+		t.Errorf("expected error for unknown value")
+	}
+	if len(m.observations) != 2 || m.Stats().Rejected != 2 {
+		t.Errorf("rejected records should not be observed, stats %+v", m.Stats())
+	}
+}
+
+func TestRecordAllocations(t *testing.T) {
+	m := newTestMetrics()
+	m.observations = make([]*Observation, 0, 1000)
+	bound := m.With(Labels{"host": "a"}).Namespace("app", "http")
+	values := Values{"requests": 1, "latency": 0.5}
+
+	// the observation and the boxed values, the row comes from a shared chunk
+	allocs := testing.AllocsPerRun(100, func() {
+		bound.Record(values)
+	})
+	if allocs > 3 {
+		t.Errorf("expected at most 3 allocations per record, got %v", allocs)
+	}
+
+	for _, ob := range m.observations[:2] {
+		if ob.values[0] != float64(1) || ob.values[1] != 0.5 || len(ob.values) != 2 || cap(ob.values) != 2 {
+			t.Errorf("unexpected values %v", ob.values)
+		}
+	}
+}
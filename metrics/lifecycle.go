@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrMetricsClosed = errors.New("metrics is closed")
+
+// Start flushes the metrics every push interval until ctx is done or the metrics is closed,
+// the metrics created by CreateMetrics, GetMetrics and NewMetrics are already started.
+// The pending observations are flushed once ctx is done, then the metrics can be started again.
+func (m *Metrics) Start(ctx context.Context) error {
+	m.runLock.Lock()
+	defer m.runLock.Unlock()
+
+	if m.isClosed() {
+		return ErrMetricsClosed
+	}
+	if m.stop != nil {
+		select {
+		case <-m.done:
+			// the context of the previous run is done, wait for its final flush
+			<-m.stopped
+			m.stop = nil
+		default:
+			return fmt.Errorf("metrics %s is already started", m.name)
+		}
+	}
+
+	ctx, stop := context.WithCancel(ctx)
+	m.stop = stop
+	m.done = ctx.Done()
+	m.stopped = make(chan struct{})
+	go m.run(ctx, m.stopped)
+	return nil
+}
+
+func (m *Metrics) run(ctx context.Context, stopped chan struct{}) {
+	defer close(stopped)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.Flush()
+		case <-ctx.Done():
+			// Close flushes the metrics itself
+			if !m.isClosed() {
+				m.Flush()
+			}
+			return
+		}
+	}
+}
+
+// Close stops the periodic flush and flushes the pending observations one last time.
+// It returns ctx.Err() if ctx is done before the final flush completes, Observe fails once closed.
+func (m *Metrics) Close(ctx context.Context) error {
+	m.runLock.Lock()
+	defer m.runLock.Unlock()
+
+	m.lock.Lock()
+	if m.closed {
+		m.lock.Unlock()
+		return nil
+	}
+	m.closed = true
+	m.lock.Unlock()
+
+	if m.stop != nil {
+		m.stop()
+		select {
+		case <-m.stopped:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		m.Flush()
+	}()
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("final flush of metrics %s did not complete: %w", m.name, ctx.Err())
+	}
+}
+
+func (m *Metrics) isClosed() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.closed
+}
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timeplus-io/go-client/timeplus"
+)
+
+func TestCloseFlushes(t *testing.T) {
+	var lock sync.Mutex
+	rows := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data timeplus.IngestData
+		json.NewDecoder(r.Body).Decode(&data)
+		lock.Lock()
+		rows += len(data.Data)
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	registry := NewRegistry()
+	for _, name := range []string{"a", "b"} {
+		m := newTestMetrics()
+		m.name = name
+		m.timeplusClient = timeplus.NewCient(server.URL, "", "key")
+		m.interval = time.Hour
+		if err := m.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Start(context.Background()); err == nil {
+			t.Errorf("expected error starting twice")
+		}
+		if err := registry.Register(m); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Observe("app", "test", []any{"h"}, []any{1.0, nil}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := registry.Close(ctx); err != nil {
+		t.Fatalf("failed to close: %s", err)
+	}
+
+	lock.Lock()
+	if rows != 2 {
+		t.Errorf("expected 2 rows flushed on close, got %d", rows)
+	}
+	lock.Unlock()
+
+	m := newTestMetrics()
+	m.Close(ctx)
+	if err := m.Observe("app", "test", []any{"h"}, []any{1.0, nil}, nil); err != ErrMetricsClosed {
+		t.Errorf("expected ErrMetricsClosed, got %v", err)
+	}
+	if err := m.Start(ctx); err != ErrMetricsClosed {
+		t.Errorf("expected ErrMetricsClosed, got %v", err)
+	}
+}
+
+func TestStartAgainAfterContextDone(t *testing.T) {
+	flushed := make(chan int, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data timeplus.IngestData
+		json.NewDecoder(r.Body).Decode(&data)
+		flushed <- len(data.Data)
+	}))
+	defer server.Close()
+
+	m := newTestMetrics()
+	m.timeplusClient = timeplus.NewCient(server.URL, "", "key")
+	m.interval = time.Hour
+	counter, err := m.NewCounter("requests")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := m.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	counter.Inc("app", "test", []any{"h"})
+	cancel()
+
+	select {
+	case rows := <-flushed:
+		if rows != 1 {
+			t.Errorf("expected 1 row flushed when the context is done, got %d", rows)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("metrics not flushed when the context is done")
+	}
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Errorf("expected the metrics to start again, got %s", err)
+	}
+	if err := m.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := counter.Inc("app", "test", []any{"h"}); err != ErrMetricsClosed {
+		t.Errorf("expected ErrMetricsClosed from a closed counter, got %v", err)
+	}
+}
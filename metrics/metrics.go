@@ -1,7 +1,9 @@
 package metrics
 
 import (
+	"context"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
@@ -14,22 +16,58 @@ const DefaultLogStoreRetentionMS = 1342177280
 
 const NumberOfInternalFields = 1
 
+// NumberOfBaseFields is the number of columns before the tags, which are timestamp, namespace, subsystem and extra tags
+const NumberOfBaseFields = 4
+
+// rowsPerChunk is how many rows of observations are allocated at once
+const rowsPerChunk = 64
+
+const TagColumnType = "string"
+const ValueColumnType = "float64"
+
+// Client is the part of timeplus.Client used by the metrics, the schema of their stream is changed with sql
+type Client interface {
+	timeplus.StreamWriter
+	timeplus.SQLExecutor
+}
+
 type Metrics struct {
 	name       string
 	tagNames   []string
 	valueNames []string
+	// valueIndexes maps the value names to their position in a row
+	valueIndexes map[string]int
+	// rowBuffer is the unused part of the chunk the rows of the observations are taken from
+	rowBuffer []any
 
-	timeplusClient *timeplus.TimeplusClient
+	timeplusClient Client
 	streamName     string
 	observations   []*Observation
 	lock           sync.Mutex
 	interval       time.Duration
 	streamDef      timeplus.StreamDef
 	streamCols     []string
+	baseCols       []string
+	options        MetricsOptions
+	// stringTimestamp is set for streams of the legacy layout
+	stringTimestamp bool
+	instruments     []instrument
+
+	runLock sync.Mutex
+	stop    context.CancelFunc
+	// done is the context of the running flush, which ends with its final flush and closes stopped
+	done    <-chan struct{}
+	stopped chan struct{}
+	closed  bool
+
+	logger          Logger
+	errorHandler    ErrorHandler
+	nonFinitePolicy NonFinitePolicy
+	stats           stats
 }
 
 type Observation struct {
-	timestamp string
+	timestamp time.Time
 	namespace string
 	subsystem string
 	tags      []any
@@ -37,82 +75,111 @@ type Observation struct {
 	extraTags map[string]interface{}
 }
 
-func CreateMetrics(name string, tags []string, values []string, timeplusClient *timeplus.TimeplusClient, pushInterval time.Duration) (*Metrics, error) {
-	m := &Metrics{
-		name:           name,
-		tagNames:       tags,
-		valueNames:     values,
-		timeplusClient: timeplusClient,
-		observations:   make([]*Observation, 0),
-		lock:           sync.Mutex{},
-		interval:       pushInterval,
+func newMetrics(name string, timeplusClient Client, pushInterval time.Duration, opts MetricsOptions) *Metrics {
+	opts = opts.withDefaults()
+	return &Metrics{
+		name:            name,
+		timeplusClient:  timeplusClient,
+		streamName:      opts.StreamPrefix + name,
+		observations:    make([]*Observation, 0),
+		lock:            sync.Mutex{},
+		interval:        pushInterval,
+		options:         opts,
+		logger:          opts.Logger,
+		errorHandler:    opts.ErrorHandler,
+		nonFinitePolicy: opts.NonFinitePolicy,
 	}
-	if err := m.create(); err != nil {
+}
+
+// CreateMetrics creates a new metrics stream with the legacy layout, see CreateMetricsWithOptions
+func CreateMetrics(name string, tags []string, values []string, timeplusClient Client, pushInterval time.Duration) (*Metrics, error) {
+	return CreateMetricsWithOptions(name, tags, values, timeplusClient, pushInterval, LegacyMetricsOptions())
+}
+
+func CreateMetricsWithOptions(name string, tags []string, values []string, timeplusClient Client, pushInterval time.Duration, opts MetricsOptions) (*Metrics, error) {
+	m := newMetrics(name, timeplusClient, pushInterval, opts)
+	if err := m.create(tags, values); err != nil {
 		return nil, err
 	}
-	go m.start()
+	m.Start(context.Background())
 	return m, nil
 }
 
-func GetMetrics(name string, timeplusClient *timeplus.TimeplusClient, pushInterval time.Duration) (*Metrics, error) {
-	m := &Metrics{
-		name:           name,
-		timeplusClient: timeplusClient,
-		observations:   make([]*Observation, 0),
-		lock:           sync.Mutex{},
-		interval:       pushInterval,
-	}
+func GetMetrics(name string, timeplusClient Client, pushInterval time.Duration) (*Metrics, error) {
+	return GetMetricsWithOptions(name, timeplusClient, pushInterval, LegacyMetricsOptions())
+}
+
+// GetMetricsWithOptions loads an existing metrics stream, the layout is taken from the stream
+// so only the stream prefix and the non layout options apply
+func GetMetricsWithOptions(name string, timeplusClient Client, pushInterval time.Duration, opts MetricsOptions) (*Metrics, error) {
+	m := newMetrics(name, timeplusClient, pushInterval, opts)
 	if err := m.get(); err != nil {
 		return nil, err
 	}
-	go m.start()
+	m.Start(context.Background())
 	return m, nil
 }
 
-func NewMetrics(name string, tags []string, values []string, timeplusClient *timeplus.TimeplusClient, pushInterval time.Duration) (*Metrics, error) {
-	var m *Metrics
-	m, err := GetMetrics(name, timeplusClient, pushInterval)
-	if err != nil {
-		if m, err := CreateMetrics(name, tags, values, timeplusClient, pushInterval); err != nil {
+// NewMetrics creates the metrics stream or reconciles the existing one with tags and values,
+// see Metrics.reconcile for how the schema of an existing stream changes
+func NewMetrics(name string, tags []string, values []string, timeplusClient Client, pushInterval time.Duration) (*Metrics, error) {
+	return NewMetricsWithOptions(name, tags, values, timeplusClient, pushInterval, LegacyMetricsOptions())
+}
+
+func NewMetricsWithOptions(name string, tags []string, values []string, timeplusClient Client, pushInterval time.Duration, opts MetricsOptions) (*Metrics, error) {
+	m := newMetrics(name, timeplusClient, pushInterval, opts)
+	if m.timeplusClient.ExistStream(m.streamName) {
+		if err := m.getMetricStream(); err != nil {
+			return nil, err
+		}
+		if err := m.reconcile(tags, values); err != nil {
 			return nil, err
-		} else {
-			return m, nil
 		}
+	} else if err := m.create(tags, values); err != nil {
+		return nil, err
 	}
+	m.Start(context.Background())
 	return m, nil
 }
 
 func (m *Metrics) createMetricStream() error {
+	opts := m.options
+	timestampType := TimestampColumnType
+	eventTimeColumn := opts.Columns.Timestamp
+	if opts.LegacyLayout {
+		timestampType = "string"
+		eventTimeColumn = fmt.Sprintf("to_datetime64(%s,9)", opts.Columns.Timestamp)
+	}
+
 	streamDef := timeplus.StreamDef{
-		Name: m.streamName,
+		Name:        m.streamName,
+		Description: encodeSchema(m.schema()),
 		Columns: []timeplus.ColumnDef{
 			{
-				Name: "timestamp",
-				Type: "string",
+				Name: opts.Columns.Timestamp,
+				Type: timestampType,
 			},
 			{
-				Name: "namepsace",
+				Name: opts.Columns.Namespace,
 				Type: "string",
 			},
 			{
-				Name: "subsystem",
+				Name: opts.Columns.Subsystem,
 				Type: "string",
 			},
 			{
-				Name: "tags",
+				Name: opts.Columns.ExtraTags,
 				Type: "json",
 			},
 		},
-		EventTimeColumn:        "to_datetime64(timestamp,9)",
-		TTLExpression:          DefaultTTL,
-		LogStoreRetentionBytes: DefaultLogStoreRetentionBytes,
-		LogStoreRetentionMS:    DefaultLogStoreRetentionMS,
+		EventTimeColumn: eventTimeColumn,
 	}
+	SetRetention(&streamDef, opts.TTLExpression, opts.LogStoreRetentionBytes, opts.LogStoreRetentionMS)
 
 	for _, name := range m.tagNames {
 		col := timeplus.ColumnDef{
 			Name: name,
-			Type: "string",
+			Type: TagColumnType,
 		}
 		streamDef.Columns = append(streamDef.Columns, col)
 	}
@@ -120,19 +187,25 @@ func (m *Metrics) createMetricStream() error {
 	for _, value := range m.valueNames {
 		col := timeplus.ColumnDef{
 			Name: value,
-			Type: "float64",
+			Type: ValueColumnType,
 		}
 		streamDef.Columns = append(streamDef.Columns, col)
 	}
 
 	m.streamDef = streamDef
-	m.streamCols = m.getCols()
-	fmt.Printf("the stream cols is %v\n", m.streamCols)
+	m.baseCols = m.getCols()[0:NumberOfBaseFields]
+	m.stringTimestamp = opts.LegacyLayout
+	m.streamCols = m.getStreamCols()
 	return m.timeplusClient.CreateStream(streamDef)
 }
 
-func (m *Metrics) create() error {
-	m.streamName = fmt.Sprintf("_tp_metric_%s", m.name)
+func (m *Metrics) create(tags []string, values []string) error {
+	if err := m.validateSchema(tags, values); err != nil {
+		return err
+	}
+	m.tagNames = tags
+	m.setValueNames(values)
+
 	if m.timeplusClient.ExistStream(m.streamName) {
 		return fmt.Errorf("metrics stream already exist")
 	} else {
@@ -147,39 +220,21 @@ func (m *Metrics) getMetricStream() error {
 	}
 
 	m.streamDef = *stream
-	allCols := m.getCols()
-
-	m.streamCols = allCols[0 : len(allCols)-NumberOfInternalFields]
-	m.tagNames = m.getTagNames()
-	m.valueNames = m.getValueNames()
-	fmt.Printf("the stream cols is %v\n", m.streamCols)
-	return nil
-}
-
-func (m *Metrics) getTagNames() []string {
-	result := make([]string, 0)
-	cols := m.streamDef.Columns[4 : len(m.streamDef.Columns)-NumberOfInternalFields]
-	for _, col := range cols {
-		if col.Type == "string" {
-			result = append(result, col.Name)
-		}
+	if len(m.streamDef.Columns) < NumberOfBaseFields {
+		return fmt.Errorf("stream %s is not a metrics stream", m.streamName)
 	}
-	return result
-}
 
-func (m *Metrics) getValueNames() []string {
-	result := make([]string, 0)
-	cols := m.streamDef.Columns[4 : len(m.streamDef.Columns)-NumberOfInternalFields]
-	for _, col := range cols {
-		if col.Type == "float64" {
-			result = append(result, col.Name)
-		}
-	}
-	return result
+	schema := m.loadSchema()
+	m.baseCols = m.getCols()[0:NumberOfBaseFields]
+	// streams created with the legacy layout keep the timestamp as a string of nanoseconds
+	m.stringTimestamp = m.streamDef.Columns[0].Type == "string"
+	m.tagNames = schema.Tags
+	m.setValueNames(schema.Values)
+	m.streamCols = m.getStreamCols()
+	return nil
 }
 
 func (m *Metrics) get() error {
-	m.streamName = fmt.Sprintf("_tp_metric_%s", m.name)
 	if !m.timeplusClient.ExistStream(m.streamName) {
 		return fmt.Errorf("metrics stream does not exist")
 	} else {
@@ -187,20 +242,27 @@ func (m *Metrics) get() error {
 	}
 }
 
-func (m *Metrics) start() {
-	for {
-		m.Flush()
-		time.Sleep(m.interval)
+func (m *Metrics) getObservations() []*Observation {
+	m.lock.Lock()
+	obs := m.observations
+	m.observations = make([]*Observation, 0)
+	instruments := m.instruments
+	m.lock.Unlock()
+
+	timestamp := time.Now()
+	for _, inst := range instruments {
+		collected := inst.collect(timestamp)
+		m.stats.recordObserved(len(collected))
+		obs = append(obs, collected...)
 	}
+	return obs
 }
 
-func (m *Metrics) getObservations() []*Observation {
+func (m *Metrics) register(inst instrument) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	obs := m.observations
-	m.observations = make([]*Observation, 0)
-	return obs
+	m.instruments = append(m.instruments, inst)
 }
 
 func (m *Metrics) getCols() []string {
@@ -211,6 +273,15 @@ func (m *Metrics) getCols() []string {
 	return cols
 }
 
+// getStreamCols returns the columns written by the metrics in the order of toIngestRow
+func (m *Metrics) getStreamCols() []string {
+	cols := make([]string, 0, len(m.baseCols)+len(m.tagNames)+len(m.valueNames))
+	cols = append(cols, m.baseCols...)
+	cols = append(cols, m.tagNames...)
+	cols = append(cols, m.valueNames...)
+	return cols
+}
+
 func (m *Metrics) toIngestPayload(obs []*Observation) *timeplus.IngestPayload {
 	payload := &timeplus.IngestPayload{
 		Stream: m.streamName,
@@ -228,7 +299,11 @@ func (m *Metrics) toIngestPayload(obs []*Observation) *timeplus.IngestPayload {
 
 func (m *Metrics) toIngestRow(ob *Observation) []any {
 	row := make([]any, 0)
-	row = append(row, ob.timestamp)
+	if m.stringTimestamp {
+		row = append(row, strconv.FormatInt(ob.timestamp.UnixNano(), 10))
+	} else {
+		row = append(row, ob.timestamp.UTC().Format(TimestampFormat))
+	}
 	row = append(row, ob.namespace)
 	row = append(row, ob.subsystem)
 	row = append(row, ob.extraTags)
@@ -237,24 +312,62 @@ func (m *Metrics) toIngestRow(ob *Observation) []any {
 	return row
 }
 
-// Note, the tags could be nil or string
-// Note, the values could be nil or float64
+// Note, the tags could be nil, string, fmt.Stringer, bool or integer, they are stored as string
+// Note, the values could be nil or any integer or float, they are stored as float64
 func (m *Metrics) Observe(namepsace string, subsystem string, tags []any, values []any, extraTags map[string]interface{}) error {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	if len(tags) != len(m.tagNames) {
-		return fmt.Errorf("the number of tags does not match, should be %d", len(m.tagNames))
+	if m.closed {
+		m.stats.recordRejected()
+		return ErrMetricsClosed
 	}
 
-	if len(values) != len(m.valueNames) {
-		return fmt.Errorf("the number of valus does not match, should be %d", len(m.valueNames))
+	tags, err := m.validateTags(tags)
+	if err != nil {
+		m.stats.recordRejected()
+		return err
 	}
 
-	// TODO: should validate the tags are string/nil and values are number/nil
+	values, err = m.validateValues(values)
+	if err != nil {
+		m.stats.recordRejected()
+		return err
+	}
 
+	if err := validateExtraTags(extraTags); err != nil {
+		m.stats.recordRejected()
+		return err
+	}
+
+	m.appendObservation(time.Now(), namepsace, subsystem, tags, values, extraTags)
+	return nil
+}
+
+// appendObservation adds validated tags and values, the caller has to hold the lock
+func (m *Metrics) setValueNames(values []string) {
+	m.valueNames = values
+	m.valueIndexes = make(map[string]int, len(values))
+	for index, name := range values {
+		m.valueIndexes[name] = index
+	}
+}
+
+// newRow returns a row of nil values, the rows are taken from chunks so recording does not allocate
+// a row each time. The caller has to hold the lock.
+func (m *Metrics) newRow() []any {
+	size := len(m.valueNames)
+	if len(m.rowBuffer) < size {
+		m.rowBuffer = make([]any, size*rowsPerChunk)
+	}
+	row := m.rowBuffer[:size:size]
+	m.rowBuffer = m.rowBuffer[size:]
+	return row
+}
+
+func (m *Metrics) appendObservation(timestamp time.Time, namepsace string, subsystem string, tags []any, values []any, extraTags map[string]interface{}) {
 	ob := &Observation{
-		timestamp: fmt.Sprintf("%d", time.Now().UnixNano()),
+		timestamp: timestamp,
 		namespace: namepsace,
 		subsystem: subsystem,
 		tags:      tags,
@@ -262,15 +375,30 @@ func (m *Metrics) Observe(namepsace string, subsystem string, tags []any, values
 		extraTags: extraTags,
 	}
 	m.observations = append(m.observations, ob)
-	return nil
+	m.stats.recordObserved(1)
 }
 
+// Flush sends the pending observations, a failed batch is dropped and reported to the error handler
 func (m *Metrics) Flush() {
+	if err := m.FlushWithError(); err != nil {
+		m.handleError(err)
+	}
+}
+
+// FlushWithError sends the pending observations and returns the error of a failed batch
+// instead of reporting it to the error handler, the failed batch is dropped
+func (m *Metrics) FlushWithError() error {
 	obs := m.getObservations()
-	if len(obs) > 0 {
-		payload := m.toIngestPayload(obs)
-		if err := m.timeplusClient.InsertData(payload); err != nil {
-			fmt.Printf("failed to ingest metrics data %s", err)
-		}
+	if len(obs) == 0 {
+		return nil
 	}
+
+	payload := m.toIngestPayload(obs)
+	start := time.Now()
+	err := m.timeplusClient.InsertData(payload)
+	m.stats.recordFlush(len(obs), time.Since(start), err)
+	if err != nil {
+		return fmt.Errorf("failed to ingest %d rows of metrics %s: %w", len(obs), m.name, err)
+	}
+	return nil
 }
@@ -1,40 +1,54 @@
 package metrics_test
 
 import (
-	"fmt"
-	"os"
+	"context"
 	"testing"
 	"time"
 
 	"github.com/timeplus-io/go-client/metrics"
-	"github.com/timeplus-io/go-client/timeplus"
+	"github.com/timeplus-io/go-client/timeplustest"
 )
 
 func TestMetric(t *testing.T) {
-	timeplusAddress := os.Getenv("TIMEPLUS_ADDRESS")
-	timeplusApiKey := os.Getenv("TIMEPLUS_API_KEY")
-	timeplusTenant := os.Getenv("TIMEPLUS_TENANT")
+	server := timeplustest.NewServer()
+	defer server.Close()
 
-	timeplusClient := timeplus.NewCient(timeplusAddress, timeplusTenant, timeplusApiKey)
-	var m *metrics.Metrics
-	m, err := metrics.CreateMetrics("cpu", []string{"a", "x", "g"}, []string{"value"}, timeplusClient, 1*time.Second)
+	timeplusClient := server.Client()
+	m, err := metrics.CreateMetrics("cpu", []string{"a", "x", "g"}, []string{"value"}, timeplusClient, time.Hour)
 	if err != nil {
-		fmt.Printf("failed to create metric, %s\n", err)
-		m, err = metrics.GetMetrics("cpu", timeplusClient, 1*time.Second)
-		if err != nil {
-			fmt.Printf("failed to get metric, %s\n", err)
-			return
-		}
-	} else {
-		time.Sleep(3 * time.Second)
+		t.Fatalf("failed to create metric, %s", err)
+	}
+	if _, err := metrics.CreateMetrics("cpu", []string{"a"}, []string{"value"}, timeplusClient, time.Hour); err == nil {
+		t.Errorf("expected error creating an existing metric")
 	}
 
 	if err = m.Observe("timeplus", "test", []any{"xxx", "xxx", nil}, []any{128.9}, nil); err != nil {
-		fmt.Printf("failed to observer %s\n", err)
+		t.Errorf("failed to observe %s", err)
 	}
 	m.Observe("timeplus", "test", []any{"xxx", "xxx", "xxx"}, []any{12.3}, map[string]interface{}{"a": "b"})
 	m.Observe("timeplus", "x1", []any{"xxx", "xxx", "xxx"}, []any{0}, nil)
 	m.Observe("timeplus", "x1", []any{"xxx", "xxx", "xxx"}, []any{nil}, nil)
+	if err := m.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
 
-	time.Sleep(3 * time.Second)
+	rows := server.Rows("_tp_metric_cpu")
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows, got %v", rows)
+	}
+	if rows[0]["namepsace"] != "timeplus" || rows[0]["g"] != nil || rows[0]["value"] != 128.9 {
+		t.Errorf("unexpected first row %v", rows[0])
+	}
+	if rows[1]["tags"].(map[string]any)["a"] != "b" || rows[3]["value"] != nil {
+		t.Errorf("unexpected rows %v", rows)
+	}
+
+	m, err = metrics.GetMetrics("cpu", timeplusClient, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to get metric, %s", err)
+	}
+	defer m.Close(context.Background())
+	if err := m.Observe("timeplus", "test", []any{"xxx"}, []any{1}, nil); err == nil {
+		t.Errorf("expected error for wrong number of tags")
+	}
 }
@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/timeplus-io/go-client/timeplus"
+)
+
+const DefaultStreamPrefix = "_tp_metric_"
+
+// TimestampColumnType is declared in UTC, as the timestamps are written in UTC without zone
+const TimestampColumnType = "datetime64(9, 'UTC')"
+const TimestampFormat = "2006-01-02 15:04:05.000000000"
+
+// DefaultSeriesIdleTimeout drops the series of an instrument which have not been updated for 10 minutes,
+// DefaultSummaryMaxSamples bounds the samples a summary keeps for each series within an interval
+const (
+	DefaultSeriesIdleTimeout = 10 * time.Minute
+	DefaultSummaryMaxSamples = 1024
+)
+
+// NoTTL and NoRetention disable the TTL and the log store retention of a stream,
+// since the zero values of the options take the defaults
+const (
+	NoTTL       = "none"
+	NoRetention = -1
+)
+
+// SetRetention sets the TTL and the log store retention of def, NoTTL and NoRetention leave them unset
+func SetRetention(def *timeplus.StreamDef, ttlExpression string, retentionBytes int, retentionMS int) {
+	def.TTLExpression = ttlExpression
+	if ttlExpression == NoTTL {
+		def.TTLExpression = ""
+	}
+	def.LogStoreRetentionBytes = 0
+	if retentionBytes != NoRetention {
+		def.LogStoreRetentionBytes = retentionBytes
+	}
+	def.LogStoreRetentionMS = 0
+	if retentionMS != NoRetention {
+		def.LogStoreRetentionMS = retentionMS
+	}
+}
+
+// ColumnNames are the names of the columns every metrics stream has before its tags and values
+type ColumnNames struct {
+	Timestamp string
+	Namespace string
+	Subsystem string
+	ExtraTags string
+}
+
+// MetricsOptions controls the layout of the metrics stream created and how the metrics reports problems.
+// The zero value of a field takes the default of DefaultMetricsOptions.
+type MetricsOptions struct {
+	StreamPrefix string
+	// TTLExpression, LogStoreRetentionBytes and LogStoreRetentionMS are disabled with NoTTL and NoRetention
+	TTLExpression          string
+	LogStoreRetentionBytes int
+	LogStoreRetentionMS    int
+	Columns                ColumnNames
+	// LegacyLayout creates the stream the way CreateMetrics did before options existed,
+	// with the timestamp stored as a string of nanoseconds and the namespace column named namepsace
+	LegacyLayout bool
+
+	// SeriesIdleTimeout drops the series of the instruments which are not updated for that long, so label
+	// values which are not used anymore do not grow the memory. A counter updated again after being dropped
+	// restarts from zero. A negative timeout keeps the series forever.
+	SeriesIdleTimeout time.Duration
+	// SummaryMaxSamples is the number of samples a summary keeps for each series within an interval,
+	// beyond it the quantiles are calculated from a uniform sample of the observations
+	SummaryMaxSamples int
+
+	Logger          Logger
+	ErrorHandler    ErrorHandler
+	NonFinitePolicy NonFinitePolicy
+}
+
+// DefaultMetricsOptions uses a native datetime64(9, 'UTC') timestamp column as event time
+func DefaultMetricsOptions() MetricsOptions {
+	return MetricsOptions{
+		StreamPrefix:           DefaultStreamPrefix,
+		TTLExpression:          DefaultTTL,
+		LogStoreRetentionBytes: DefaultLogStoreRetentionBytes,
+		LogStoreRetentionMS:    DefaultLogStoreRetentionMS,
+		SeriesIdleTimeout:      DefaultSeriesIdleTimeout,
+		SummaryMaxSamples:      DefaultSummaryMaxSamples,
+		Columns: ColumnNames{
+			Timestamp: "timestamp",
+			Namespace: "namespace",
+			Subsystem: "subsystem",
+			ExtraTags: "tags",
+		},
+	}
+}
+
+// LegacyMetricsOptions is the layout used by CreateMetrics, GetMetrics and NewMetrics,
+// it stays compatible with the metrics streams created by earlier versions
+func LegacyMetricsOptions() MetricsOptions {
+	opts := DefaultMetricsOptions()
+	opts.Columns.Namespace = "namepsace"
+	opts.LegacyLayout = true
+	return opts
+}
+
+func (o MetricsOptions) withDefaults() MetricsOptions {
+	defaults := DefaultMetricsOptions()
+	if o.LegacyLayout {
+		defaults = LegacyMetricsOptions()
+	}
+
+	if len(o.StreamPrefix) == 0 {
+		o.StreamPrefix = defaults.StreamPrefix
+	}
+	if len(o.TTLExpression) == 0 {
+		o.TTLExpression = defaults.TTLExpression
+	}
+	if o.LogStoreRetentionBytes == 0 {
+		o.LogStoreRetentionBytes = defaults.LogStoreRetentionBytes
+	}
+	if o.LogStoreRetentionMS == 0 {
+		o.LogStoreRetentionMS = defaults.LogStoreRetentionMS
+	}
+	if o.SeriesIdleTimeout == 0 {
+		o.SeriesIdleTimeout = defaults.SeriesIdleTimeout
+	}
+	if o.SummaryMaxSamples <= 0 {
+		o.SummaryMaxSamples = defaults.SummaryMaxSamples
+	}
+	if len(o.Columns.Timestamp) == 0 {
+		o.Columns.Timestamp = defaults.Columns.Timestamp
+	}
+	if len(o.Columns.Namespace) == 0 {
+		o.Columns.Namespace = defaults.Columns.Namespace
+	}
+	if len(o.Columns.Subsystem) == 0 {
+		o.Columns.Subsystem = defaults.Columns.Subsystem
+	}
+	if len(o.Columns.ExtraTags) == 0 {
+		o.Columns.ExtraTags = defaults.Columns.ExtraTags
+	}
+	return o
+}
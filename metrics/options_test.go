@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timeplus-io/go-client/timeplus"
+	"github.com/timeplus-io/go-client/timeplustest"
+)
+
+func TestMetricsOptions(t *testing.T) {
+	var created timeplus.StreamDef
+	var ingested timeplus.IngestData
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			w.Write([]byte("[]"))
+		case strings.HasSuffix(r.URL.Path, "/ingest"):
+			json.NewDecoder(r.Body).Decode(&ingested)
+		case strings.HasSuffix(r.URL.Path, "/streams"):
+			json.NewDecoder(r.Body).Decode(&created)
+		}
+	}))
+	defer server.Close()
+
+	opts := MetricsOptions{
+		StreamPrefix:  "app_metric_",
+		TTLExpression: "to_datetime(_tp_time) + INTERVAL 1 DAY",
+		Columns:       ColumnNames{ExtraTags: "labels"},
+	}
+	client := timeplus.NewCient(server.URL, "", "key")
+	m, err := NewMetricsWithOptions("cpu", []string{"host"}, []string{"value"}, client, time.Hour, opts)
+	if err != nil {
+		t.Fatalf("failed to create metrics: %s", err)
+	}
+
+	if created.Name != "app_metric_cpu" || created.TTLExpression != opts.TTLExpression || created.LogStoreRetentionMS != DefaultLogStoreRetentionMS {
+		t.Errorf("unexpected stream %+v", created)
+	}
+	if created.EventTimeColumn != "timestamp" || created.Columns[0].Type != TimestampColumnType {
+		t.Errorf("expected a native timestamp column, got %+v", created.Columns[0])
+	}
+	names := make([]string, len(created.Columns))
+	for i, col := range created.Columns {
+		names[i] = col.Name
+	}
+	if strings.Join(names, ",") != "timestamp,namespace,subsystem,labels,host,value" {
+		t.Errorf("unexpected columns %v", names)
+	}
+
+	m.Observe("app", "test", []any{"a"}, []any{1}, nil)
+	m.Close(context.Background())
+
+	if len(ingested.Data) != 1 {
+		t.Fatalf("expected 1 row ingested, got %d", len(ingested.Data))
+	}
+	if _, err := time.Parse(TimestampFormat, ingested.Data[0][0].(string)); err != nil {
+		t.Errorf("unexpected timestamp %v", ingested.Data[0][0])
+	}
+
+	legacy := LegacyMetricsOptions().withDefaults()
+	if legacy.Columns.Namespace != "namepsace" || !legacy.LegacyLayout {
+		t.Errorf("unexpected legacy options %+v", legacy)
+	}
+}
+
+func TestMetricsOptionsDisableRetention(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+
+	opts := MetricsOptions{TTLExpression: NoTTL, LogStoreRetentionBytes: NoRetention, LogStoreRetentionMS: NoRetention}
+	m, err := NewMetricsWithOptions("cpu", []string{"host"}, []string{"value"}, server.Client(), time.Hour, opts)
+	if err != nil {
+		t.Fatalf("failed to create metrics: %s", err)
+	}
+	defer m.Close(context.Background())
+
+	requests := server.Requests()
+	created := requests[len(requests)-1]
+	var def map[string]any
+	json.Unmarshal(created.Body, &def)
+	for _, field := range []string{"ttl_expression", "logstore_retention_bytes", "logstore_retention_ms"} {
+		if _, ok := def[field]; ok {
+			t.Errorf("expected %s to be unset, got %v", field, def[field])
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Registry keeps track of metrics so they can be closed at once on shutdown
+type Registry struct {
+	lock    sync.Mutex
+	metrics map[string]*Metrics
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make(map[string]*Metrics),
+	}
+}
+
+func (r *Registry) Register(m *Metrics) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.metrics[m.name]; ok {
+		return fmt.Errorf("metrics %s is already registered", m.name)
+	}
+	r.metrics[m.name] = m
+	return nil
+}
+
+func (r *Registry) Get(name string) (*Metrics, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	m, ok := r.metrics[name]
+	return m, ok
+}
+
+// Close closes all registered metrics in parallel, sharing the deadline of ctx
+func (r *Registry) Close(ctx context.Context) error {
+	r.lock.Lock()
+	all := make([]*Metrics, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		all = append(all, m)
+	}
+	r.metrics = make(map[string]*Metrics)
+	r.lock.Unlock()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(all))
+	for index, m := range all {
+		wg.Add(1)
+		go func(index int, m *Metrics) {
+			defer wg.Done()
+			errs[index] = m.Close(ctx)
+		}(index, m)
+	}
+	wg.Wait()
+
+	failed := 0
+	var first error
+	for _, err := range errs {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to close %d metrics: %w", failed, first)
+	}
+	return nil
+}
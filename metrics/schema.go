@@ -0,0 +1,219 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/timeplus-io/go-client/timeplus"
+)
+
+const metricSchemaVersion = 1
+
+// SchemaChangeTimeout is the timeout in milliseconds of the DDL altering a metrics stream
+const SchemaChangeTimeout = 10000
+
+// metricSchema is stored in the description of the metrics stream, so the tags and values
+// of an existing stream are known without inferring them from the column types
+type metricSchema struct {
+	Version int      `json:"version"`
+	Tags    []string `json:"tags"`
+	Values  []string `json:"values"`
+}
+
+type streamDescription struct {
+	Metrics *metricSchema `json:"timeplus_metrics,omitempty"`
+}
+
+func (m *Metrics) schema() metricSchema {
+	return metricSchema{
+		Version: metricSchemaVersion,
+		Tags:    m.tagNames,
+		Values:  m.valueNames,
+	}
+}
+
+func encodeSchema(schema metricSchema) string {
+	data, _ := json.Marshal(streamDescription{Metrics: &schema})
+	return string(data)
+}
+
+// loadSchema reads the schema from the stream description, streams created before the
+// schema was stored have it inferred from the column types, which is reported by explicit
+func (m *Metrics) loadSchema() metricSchema {
+	schema, _ := m.loadSchemaWithSource()
+	return schema
+}
+
+func (m *Metrics) loadSchemaWithSource() (schema metricSchema, explicit bool) {
+	var description streamDescription
+	if err := json.Unmarshal([]byte(m.streamDef.Description), &description); err == nil && description.Metrics != nil {
+		return *description.Metrics, true
+	}
+
+	schema = metricSchema{
+		Version: metricSchemaVersion,
+		Tags:    make([]string, 0),
+		Values:  make([]string, 0),
+	}
+	for _, col := range m.streamDef.Columns[NumberOfBaseFields:] {
+		if strings.HasPrefix(col.Name, "_tp_") {
+			continue
+		}
+		switch col.Type {
+		case TagColumnType:
+			schema.Tags = append(schema.Tags, col.Name)
+		case ValueColumnType:
+			schema.Values = append(schema.Values, col.Name)
+		}
+	}
+	return schema, false
+}
+
+// validateSchema checks the tag and value names are unique and do not clash with the base columns
+func (m *Metrics) validateSchema(tags []string, values []string) error {
+	seen := make(map[string]string)
+	for _, col := range m.baseColumnNames() {
+		seen[col] = "base column"
+	}
+
+	check := func(name string, kind string) error {
+		if len(name) == 0 {
+			return fmt.Errorf("%s name cannot be empty", kind)
+		}
+		if strings.HasPrefix(name, "_tp_") {
+			return fmt.Errorf("%s %s uses the reserved prefix _tp_", kind, name)
+		}
+		if existing, ok := seen[name]; ok {
+			return fmt.Errorf("%s %s of metrics %s is already used as %s", kind, name, m.name, existing)
+		}
+		seen[name] = kind
+		return nil
+	}
+
+	for _, tag := range tags {
+		if err := check(tag, "tag"); err != nil {
+			return err
+		}
+	}
+	for _, value := range values {
+		if err := check(value, "value"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Metrics) baseColumnNames() []string {
+	if len(m.baseCols) > 0 {
+		return m.baseCols
+	}
+	columns := m.options.Columns
+	return []string{columns.Timestamp, columns.Namespace, columns.Subsystem, columns.ExtraTags}
+}
+
+// reconcile makes the existing metrics stream fit tags and values. The missing tags and values
+// are added as new columns, the ones no longer requested are kept in the stream but not written
+// and are removed from the stored schema. Turning a tag into a value or the other way around is
+// refused since the column type cannot change.
+func (m *Metrics) reconcile(tags []string, values []string) error {
+	if err := m.validateSchema(tags, values); err != nil {
+		return err
+	}
+
+	schema, explicit := m.loadSchemaWithSource()
+	for _, tag := range tags {
+		if indexOf(schema.Values, tag) >= 0 {
+			return fmt.Errorf("incompatible schema change of metrics %s, %s is a value and cannot become a tag", m.name, tag)
+		}
+	}
+	for _, value := range values {
+		if indexOf(schema.Tags, value) >= 0 {
+			return fmt.Errorf("incompatible schema change of metrics %s, %s is a tag and cannot become a value", m.name, value)
+		}
+	}
+
+	columnTypes := make(map[string]string)
+	for _, col := range m.streamDef.Columns {
+		columnTypes[col.Name] = col.Type
+	}
+
+	added := make([]timeplus.ColumnDef, 0)
+	addMissing := func(names []string, current []string, colType string) error {
+		for _, name := range names {
+			if indexOf(current, name) >= 0 {
+				continue
+			}
+			if existingType, ok := columnTypes[name]; ok {
+				if existingType != colType {
+					return fmt.Errorf("incompatible schema change of metrics %s, column %s has type %s instead of %s", m.name, name, existingType, colType)
+				}
+			} else {
+				added = append(added, timeplus.ColumnDef{Name: name, Type: colType})
+			}
+		}
+		return nil
+	}
+
+	if err := addMissing(tags, schema.Tags, TagColumnType); err != nil {
+		return err
+	}
+	if err := addMissing(values, schema.Values, ValueColumnType); err != nil {
+		return err
+	}
+	changed := !explicit || strings.Join(schema.Tags, ",") != strings.Join(tags, ",") ||
+		strings.Join(schema.Values, ",") != strings.Join(values, ",")
+	schema.Tags = tags
+	schema.Values = values
+
+	if len(added) > 0 {
+		if err := m.addColumns(added); err != nil {
+			return err
+		}
+		m.streamDef.Columns = append(m.streamDef.Columns, added...)
+	}
+
+	if changed {
+		m.storeSchema(encodeSchema(schema))
+	}
+
+	m.tagNames = tags
+	m.setValueNames(values)
+	m.streamCols = m.getStreamCols()
+	return nil
+}
+
+// storeSchema keeps the schema in the comment of the stream, which is read back as its description.
+// A server which cannot change the comment is reported but does not fail the metrics, the schema is
+// inferred from the column types instead.
+func (m *Metrics) storeSchema(description string) {
+	sql := fmt.Sprintf("ALTER STREAM %s MODIFY COMMENT %s", timeplus.QuoteIdentifier(m.streamName), timeplus.QuoteString(description))
+	if _, err := m.timeplusClient.ExecSQL(sql, SchemaChangeTimeout); err != nil {
+		m.handleError(fmt.Errorf("failed to store schema of metrics %s, it is inferred from the column types: %w", m.name, err))
+		return
+	}
+
+	stream, err := m.timeplusClient.GetStream(m.streamName)
+	if err != nil {
+		m.handleError(fmt.Errorf("failed to verify schema of metrics %s: %w", m.name, err))
+		return
+	}
+	if stream.Description != description {
+		m.handleError(fmt.Errorf("schema of metrics %s is not kept as stream description, it is inferred from the column types", m.name))
+		return
+	}
+	m.streamDef.Description = description
+}
+
+func (m *Metrics) addColumns(cols []timeplus.ColumnDef) error {
+	clauses := make([]string, len(cols))
+	for index, col := range cols {
+		clauses[index] = fmt.Sprintf("ADD COLUMN %s %s", timeplus.QuoteIdentifier(col.Name), col.Type)
+	}
+
+	sql := fmt.Sprintf("ALTER STREAM %s %s", timeplus.QuoteIdentifier(m.streamName), strings.Join(clauses, ", "))
+	if _, err := m.timeplusClient.ExecSQL(sql, SchemaChangeTimeout); err != nil {
+		return fmt.Errorf("failed to add columns to metrics %s: %w", m.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timeplus-io/go-client/timeplus"
+)
+
+func legacyStream() timeplus.StreamDef {
+	return timeplus.StreamDef{
+		Name: "_tp_metric_cpu",
+		Columns: []timeplus.ColumnDef{
+			{Name: "timestamp", Type: "string"},
+			{Name: "namepsace", Type: "string"},
+			{Name: "subsystem", Type: "string"},
+			{Name: "tags", Type: "json"},
+			{Name: "a", Type: "string"},
+			{Name: "x", Type: "string"},
+			{Name: "value", Type: "float64"},
+			{Name: "_tp_time", Type: "datetime64(3, 'UTC')"},
+		},
+	}
+}
+
+// newSchemaServer serves legacyStream and records the sql statements, the stream comment is kept
+// as description unless keepComment is false
+func newSchemaServer(keepComment bool) (*httptest.Server, func() []string) {
+	var lock sync.Mutex
+	statements := make([]string, 0)
+	description := ""
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/streams"):
+			stream := legacyStream()
+			stream.Description = description
+			json.NewEncoder(w).Encode([]timeplus.StreamDef{stream})
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/sql"):
+			var req timeplus.SQLRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			statements = append(statements, req.SQL)
+			if comment, ok := strings.CutPrefix(req.SQL, "ALTER STREAM `_tp_metric_cpu` MODIFY COMMENT "); ok && keepComment {
+				description = strings.Trim(comment, "'")
+			}
+		}
+	}))
+	return server, func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string{}, statements...)
+	}
+}
+
+func TestReconcileSchema(t *testing.T) {
+	server, recorded := newSchemaServer(true)
+	defer server.Close()
+
+	client := timeplus.NewCient(server.URL, "", "key")
+	m, err := NewMetrics("cpu", []string{"x", "host"}, []string{"value", "max"}, client, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to reconcile: %s", err)
+	}
+	defer m.Close(context.Background())
+
+	expectedCols := []string{"timestamp", "namepsace", "subsystem", "tags", "x", "host", "value", "max"}
+	if strings.Join(m.streamCols, ",") != strings.Join(expectedCols, ",") {
+		t.Errorf("unexpected stream columns %v", m.streamCols)
+	}
+
+	statements := recorded()
+	if len(statements) != 2 {
+		t.Fatalf("expected 2 statements, got %v", statements)
+	}
+	if statements[0] != "ALTER STREAM `_tp_metric_cpu` ADD COLUMN `host` string, ADD COLUMN `max` float64" {
+		t.Errorf("unexpected alter statement %s", statements[0])
+	}
+	if !strings.HasPrefix(statements[1], "ALTER STREAM `_tp_metric_cpu` MODIFY COMMENT ") {
+		t.Errorf("unexpected comment statement %s", statements[1])
+	}
+
+	// the tag a is no longer requested, so it is kept as column but removed from the schema
+	schema, explicit := m.loadSchemaWithSource()
+	if !explicit || strings.Join(schema.Tags, ",") != "x,host" || strings.Join(schema.Values, ",") != "value,max" {
+		t.Errorf("unexpected stored schema %+v", schema)
+	}
+
+	if _, err := NewMetrics("cpu", []string{"value"}, []string{}, client, time.Hour); err == nil || !strings.Contains(err.Error(), "incompatible") {
+		t.Errorf("expected incompatible schema error, got %v", err)
+	}
+	if _, err := NewMetrics("cpu", []string{"a"}, []string{"a"}, client, time.Hour); err == nil {
+		t.Errorf("expected error for duplicated names")
+	}
+}
+
+func TestReconcileSchemaWithoutComment(t *testing.T) {
+	server, recorded := newSchemaServer(false)
+	defer server.Close()
+
+	failures := make([]error, 0)
+	opts := LegacyMetricsOptions()
+	opts.ErrorHandler = func(err error) { failures = append(failures, err) }
+
+	client := timeplus.NewCient(server.URL, "", "key")
+	m, err := NewMetricsWithOptions("cpu", []string{"x", "host"}, []string{"value"}, client, time.Hour, opts)
+	if err != nil {
+		t.Fatalf("a comment which is not kept should not fail the metrics: %s", err)
+	}
+	defer m.Close(context.Background())
+
+	if len(failures) != 1 || !strings.Contains(failures[0].Error(), "inferred") {
+		t.Errorf("expected the lost schema to be reported, got %v", failures)
+	}
+	if _, explicit := m.loadSchemaWithSource(); explicit {
+		t.Errorf("expected the schema to be inferred from the columns")
+	}
+	if statements := recorded(); len(statements) != 2 {
+		t.Errorf("expected the columns to be added and the comment to be tried, got %v", statements)
+	}
+}
@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Logger receives the diagnostic messages of metrics, *log.Logger satisfies it
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+// ErrorHandler is called with the errors happening in the background, such as a failed flush
+type ErrorHandler func(err error)
+
+var defaultLogger Logger = log.New(os.Stderr, "metrics: ", log.LstdFlags)
+
+// Stats is the self telemetry of a metrics, it is used to tell if the metrics delivery is failing
+type Stats struct {
+	// Observed is the number of accepted observations, including the rows collected from instruments at flush
+	Observed uint64
+	// Rejected is the number of observations refused by Observe
+	Rejected uint64
+	// Flushes is the number of ingest requests sent, FailedFlushes of which failed
+	Flushes       uint64
+	FailedFlushes uint64
+	// FlushedRows is the number of rows ingested, DroppedRows the number of rows lost by failed flushes
+	FlushedRows uint64
+	DroppedRows uint64
+
+	LastBatchSize    int
+	LastFlushLatency time.Duration
+	LastFlushTime    time.Time
+	LastError        error
+	LastErrorTime    time.Time
+}
+
+type stats struct {
+	lock sync.Mutex
+	Stats
+}
+
+func (s *stats) recordObserved(rows int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.Observed += uint64(rows)
+}
+
+func (s *stats) recordRejected() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.Rejected++
+}
+
+func (s *stats) recordFlush(rows int, latency time.Duration, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.Flushes++
+	s.LastBatchSize = rows
+	s.LastFlushLatency = latency
+	s.LastFlushTime = time.Now()
+	if err != nil {
+		s.FailedFlushes++
+		s.DroppedRows += uint64(rows)
+		s.LastError = err
+		s.LastErrorTime = s.LastFlushTime
+	} else {
+		s.FlushedRows += uint64(rows)
+	}
+}
+
+func (s *stats) snapshot() Stats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.Stats
+}
+
+// Stats returns a snapshot of the self telemetry
+func (m *Metrics) Stats() Stats {
+	return m.stats.snapshot()
+}
+
+// SetLogger replaces the logger which defaults to stderr
+func (m *Metrics) SetLogger(logger Logger) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.logger = logger
+}
+
+// SetErrorHandler replaces the default error handling, which logs the error with the logger
+func (m *Metrics) SetErrorHandler(handler ErrorHandler) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.errorHandler = handler
+}
+
+func (m *Metrics) handleError(err error) {
+	m.lock.Lock()
+	handler := m.errorHandler
+	logger := m.logger
+	m.lock.Unlock()
+
+	if handler != nil {
+		handler(err)
+		return
+	}
+	if logger == nil {
+		logger = defaultLogger
+	}
+	logger.Printf("%s", err)
+}
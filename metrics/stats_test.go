@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/timeplus-io/go-client/timeplus"
+)
+
+func TestStats(t *testing.T) {
+	fail := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	m := newTestMetrics()
+	m.timeplusClient = timeplus.NewCient(server.URL, "", "key")
+
+	var handled []error
+	m.SetErrorHandler(func(err error) {
+		handled = append(handled, err)
+	})
+
+	m.Observe("app", "test", []any{"h"}, []any{1.0, nil}, nil)
+	m.Observe("app", "test", []any{"h"}, []any{2.0, nil}, nil)
+	m.Observe("app", "test", []any{}, []any{2.0, nil}, nil)
+	m.Flush()
+
+	stats := m.Stats()
+	if stats.Observed != 2 || stats.Rejected != 1 {
+		t.Errorf("unexpected observation stats %+v", stats)
+	}
+	if stats.Flushes != 1 || stats.FailedFlushes != 1 || stats.DroppedRows != 2 || stats.LastError == nil || stats.LastBatchSize != 2 {
+		t.Errorf("unexpected failed flush stats %+v", stats)
+	}
+	if len(handled) != 1 {
+		t.Errorf("expected the error handler to be called once, got %d", len(handled))
+	}
+
+	fail = false
+	m.Observe("app", "test", []any{"h"}, []any{3.0, nil}, nil)
+	m.Flush()
+
+	stats = m.Stats()
+	if stats.Flushes != 2 || stats.FlushedRows != 1 || stats.DroppedRows != 2 || stats.LastBatchSize != 1 {
+		t.Errorf("unexpected flush stats %+v", stats)
+	}
+
+	// the rows of instruments are observed when they are collected by the flush
+	counter, err := m.NewCounter("requests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter.Add("app", "test", []any{"h"}, 1)
+	counter.Add("app", "test", []any{"i"}, 1)
+	m.Flush()
+
+	stats = m.Stats()
+	if stats.Observed != 5 || stats.FlushedRows != 3 {
+		t.Errorf("unexpected instrument stats %+v", stats)
+	}
+}
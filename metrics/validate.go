@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// NonFinitePolicy decides how Observe handles NaN and infinite values, which cannot be ingested
+type NonFinitePolicy int
+
+const (
+	// RejectNonFinite fails the observation
+	RejectNonFinite NonFinitePolicy = iota
+	// NullNonFinite stores the value as null
+	NullNonFinite
+)
+
+// SetNonFinitePolicy replaces the default RejectNonFinite policy
+func (m *Metrics) SetNonFinitePolicy(policy NonFinitePolicy) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.nonFinitePolicy = policy
+}
+
+// validateTags checks the number of tags and converts them into string or nil
+func (m *Metrics) validateTags(tags []any) ([]any, error) {
+	if len(tags) != len(m.tagNames) {
+		return nil, fmt.Errorf("the number of tags does not match, should be %d", len(m.tagNames))
+	}
+
+	result := make([]any, len(tags))
+	for index, tag := range tags {
+		value, err := toTag(tag)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag %s: %w", m.tagNames[index], err)
+		}
+		result[index] = value
+	}
+	return result, nil
+}
+
+// validateValues checks the number of values and converts them into float64 or nil
+func (m *Metrics) validateValues(values []any) ([]any, error) {
+	if len(values) != len(m.valueNames) {
+		return nil, fmt.Errorf("the number of valus does not match, should be %d", len(m.valueNames))
+	}
+
+	result := make([]any, len(values))
+	for index, v := range values {
+		value, err := toValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %s: %w", m.valueNames[index], err)
+		}
+		if value != nil {
+			if err := checkFinite(value.(float64)); err != nil {
+				if m.nonFinitePolicy != NullNonFinite {
+					return nil, fmt.Errorf("invalid value %s: %w", m.valueNames[index], err)
+				}
+				value = nil
+			}
+		}
+		result[index] = value
+	}
+	return result, nil
+}
+
+func checkFinite(v float64) error {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Errorf("%v is not a finite number", v)
+	}
+	return nil
+}
+
+func validateExtraTags(extraTags map[string]interface{}) error {
+	if extraTags == nil {
+		return nil
+	}
+	if _, err := json.Marshal(extraTags); err != nil {
+		return fmt.Errorf("invalid extra tags: %w", err)
+	}
+	return nil
+}
+
+func toTag(tag any) (any, error) {
+	switch t := tag.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return t, nil
+	case fmt.Stringer:
+		rv := reflect.ValueOf(t)
+		if rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return nil, nil
+		}
+		return t.String(), nil
+	}
+
+	rv := reflect.ValueOf(tag)
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return toTag(rv.Elem().Interface())
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	}
+	return nil, fmt.Errorf("unsupported tag type %T, should be string, fmt.Stringer, bool or integer", tag)
+}
+
+func toValue(value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return toValue(rv.Elem().Interface())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	}
+	return nil, fmt.Errorf("unsupported value type %T, should be integer or float", value)
+}
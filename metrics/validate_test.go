@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"math"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestObserveValidation(t *testing.T) {
+	m := newTestMetrics()
+	ip := net.ParseIP("10.0.0.1")
+	var nilIP *net.IP
+
+	if err := m.Observe("app", "test", []any{ip}, []any{int32(3), uint8(4)}, nil); err != nil {
+		t.Fatalf("failed to observe: %s", err)
+	}
+	if err := m.Observe("app", "test", []any{nilIP}, []any{float32(0.5), nil}, map[string]interface{}{"a": 1}); err != nil {
+		t.Fatalf("failed to observe: %s", err)
+	}
+	if m.observations[0].tags[0] != "10.0.0.1" || m.observations[0].values[0] != float64(3) || m.observations[0].values[1] != float64(4) {
+		t.Errorf("unexpected coercion %v %v", m.observations[0].tags, m.observations[0].values)
+	}
+	if m.observations[1].tags[0] != nil {
+		t.Errorf("nil stringer should be stored as nil, got %v", m.observations[1].tags[0])
+	}
+
+	cases := []struct {
+		tags      []any
+		values    []any
+		extraTags map[string]interface{}
+		expected  string
+	}{
+		{[]any{[]string{"x"}}, []any{1, 2}, nil, "invalid tag host"},
+		{[]any{"a"}, []any{"1", 2}, nil, "invalid value requests"},
+		{[]any{"a"}, []any{1, math.NaN()}, nil, "invalid value latency"},
+		{[]any{"a"}, []any{1, math.Inf(1)}, nil, "invalid value latency"},
+		{[]any{"a"}, []any{1, 2}, map[string]interface{}{"f": func() {}}, "invalid extra tags"},
+	}
+	for _, c := range cases {
+		err := m.Observe("app", "test", c.tags, c.values, c.extraTags)
+		if err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("expected error containing %q, got %v", c.expected, err)
+		}
+	}
+
+	m.SetNonFinitePolicy(NullNonFinite)
+	if err := m.Observe("app", "test", []any{"a"}, []any{1, math.NaN()}, nil); err != nil {
+		t.Fatalf("failed to observe: %s", err)
+	}
+	if last := m.observations[len(m.observations)-1]; last.values[1] != nil {
+		t.Errorf("NaN should be stored as nil, got %v", last.values[1])
+	}
+}
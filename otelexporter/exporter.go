@@ -0,0 +1,112 @@
+// Package otelexporter exports OpenTelemetry signals into Timeplus streams,
+// so they can be analyzed with streaming SQL.
+package otelexporter
+
+import (
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"github.com/timeplus-io/go-client/metrics"
+	"github.com/timeplus-io/go-client/timeplus"
+)
+
+const timestampColumnType = metrics.TimestampColumnType
+
+// StreamConfig controls the stream created by the span and log exporters, the zero value of a field
+// takes the default of the metrics package, metrics.NoTTL and metrics.NoRetention disable them
+type StreamConfig struct {
+	Stream                 string
+	TTLExpression          string
+	LogStoreRetentionBytes int
+	LogStoreRetentionMS    int
+}
+
+func (c StreamConfig) withDefaults(stream string) StreamConfig {
+	if len(c.Stream) == 0 {
+		c.Stream = stream
+	}
+	if len(c.TTLExpression) == 0 {
+		c.TTLExpression = metrics.DefaultTTL
+	}
+	if c.LogStoreRetentionBytes == 0 {
+		c.LogStoreRetentionBytes = metrics.DefaultLogStoreRetentionBytes
+	}
+	if c.LogStoreRetentionMS == 0 {
+		c.LogStoreRetentionMS = metrics.DefaultLogStoreRetentionMS
+	}
+	return c
+}
+
+// ensureStream creates the stream with columns unless it exists, the first column is used as event time
+func ensureStream(client timeplus.StreamWriter, config StreamConfig, columns []timeplus.ColumnDef) error {
+	if client.ExistStream(config.Stream) {
+		return nil
+	}
+
+	streamDef := timeplus.StreamDef{
+		Name:            config.Stream,
+		Columns:         columns,
+		EventTimeColumn: columns[0].Name,
+	}
+	metrics.SetRetention(&streamDef, config.TTLExpression, config.LogStoreRetentionBytes, config.LogStoreRetentionMS)
+	if err := client.CreateStream(streamDef); err != nil {
+		return fmt.Errorf("failed to create stream %s: %w", config.Stream, err)
+	}
+	return nil
+}
+
+func columnNames(columns []timeplus.ColumnDef) []string {
+	names := make([]string, len(columns))
+	for index, col := range columns {
+		names[index] = col.Name
+	}
+	return names
+}
+
+// formatTime formats t for a datetime64(9, 'UTC') column, the zero time is stored as null
+func formatTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(metrics.TimestampFormat)
+}
+
+// attributesToMap converts attributes into a map stored in a json column
+func attributesToMap(iter attribute.Iterator) map[string]any {
+	attrs := make(map[string]any, iter.Len())
+	for iter.Next() {
+		kv := iter.Attribute()
+		attrs[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	return attrs
+}
+
+func keyValuesToMap(kvs []attribute.KeyValue) map[string]any {
+	attrs := make(map[string]any, len(kvs))
+	for _, kv := range kvs {
+		attrs[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	return attrs
+}
+
+func resourceAttributes(res *resource.Resource) map[string]any {
+	if res == nil {
+		return map[string]any{}
+	}
+	return attributesToMap(res.Iter())
+}
+
+// serviceName returns the service.name of the resource, which is stored in its own column for filtering
+func serviceName(res *resource.Resource) string {
+	if res == nil {
+		return ""
+	}
+	if value, ok := res.Set().Value(semconv.ServiceNameKey); ok {
+		return value.AsString()
+	}
+	return ""
+}
@@ -0,0 +1,183 @@
+package otelexporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+
+	"github.com/timeplus-io/go-client/timeplus"
+)
+
+const DefaultLogStream = "otel_logs"
+
+// LogColumns is the schema of the log stream, a body which is not a string is stored as json
+var LogColumns = []timeplus.ColumnDef{
+	{Name: "timestamp", Type: timestampColumnType},
+	{Name: "observed_timestamp", Type: timestampColumnType},
+	{Name: "severity_number", Type: "int32"},
+	{Name: "severity_text", Type: "string"},
+	{Name: "body", Type: "string"},
+	{Name: "event_name", Type: "string"},
+	{Name: "trace_id", Type: "string"},
+	{Name: "span_id", Type: "string"},
+	{Name: "service", Type: "string"},
+	{Name: "scope", Type: "string"},
+	{Name: "scope_version", Type: "string"},
+	{Name: "attributes", Type: "json"},
+	{Name: "resource", Type: "json"},
+}
+
+// LogExporter inserts every batch of log records into the log stream, it is meant to be used
+// with a batch processor so each insert carries many records
+type LogExporter struct {
+	client  timeplus.StreamWriter
+	config  StreamConfig
+	columns []string
+
+	lock     sync.Mutex
+	shutdown bool
+}
+
+var _ sdklog.Exporter = (*LogExporter)(nil)
+
+// NewLogExporter creates the log stream unless it exists
+func NewLogExporter(client timeplus.StreamWriter, config StreamConfig) (*LogExporter, error) {
+	config = config.withDefaults(DefaultLogStream)
+	if err := ensureStream(client, config, LogColumns); err != nil {
+		return nil, err
+	}
+	return &LogExporter{
+		client:  client,
+		config:  config,
+		columns: columnNames(LogColumns),
+	}, nil
+}
+
+func (e *LogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	e.lock.Lock()
+	shutdown := e.shutdown
+	e.lock.Unlock()
+	if shutdown || len(records) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	rows := make([][]any, len(records))
+	for index := range records {
+		rows[index] = logRow(&records[index])
+	}
+
+	payload := &timeplus.IngestPayload{
+		Stream: e.config.Stream,
+		Data: timeplus.IngestData{
+			Columns: e.columns,
+			Data:    rows,
+		},
+	}
+	if err := e.client.InsertData(payload); err != nil {
+		return fmt.Errorf("failed to export %d log records: %w", len(records), err)
+	}
+	return nil
+}
+
+// ForceFlush does nothing since every Export is inserted right away
+func (e *LogExporter) ForceFlush(ctx context.Context) error {
+	return nil
+}
+
+func (e *LogExporter) Shutdown(ctx context.Context) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.shutdown = true
+	return nil
+}
+
+func logRow(record *sdklog.Record) []any {
+	attrs := make(map[string]any, record.AttributesLen())
+	record.WalkAttributes(func(kv otellog.KeyValue) bool {
+		attrs[kv.Key] = logValue(kv.Value)
+		return true
+	})
+
+	traceID, spanID := "", ""
+	if record.TraceID().IsValid() {
+		traceID = record.TraceID().String()
+	}
+	if record.SpanID().IsValid() {
+		spanID = record.SpanID().String()
+	}
+
+	// most log bridges only set the observed timestamp
+	timestamp := record.Timestamp()
+	if timestamp.IsZero() {
+		timestamp = record.ObservedTimestamp()
+	}
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	scope := record.InstrumentationScope()
+	return []any{
+		formatTime(timestamp),
+		formatTime(record.ObservedTimestamp()),
+		int32(record.Severity()),
+		record.SeverityText(),
+		logBody(record.Body()),
+		record.EventName(),
+		traceID,
+		spanID,
+		serviceName(record.Resource()),
+		scope.Name,
+		scope.Version,
+		attrs,
+		resourceAttributes(record.Resource()),
+	}
+}
+
+func logBody(body otellog.Value) string {
+	switch body.Kind() {
+	case otellog.KindEmpty:
+		return ""
+	case otellog.KindString:
+		return body.AsString()
+	}
+	data, _ := json.Marshal(logValue(body))
+	return string(data)
+}
+
+// logValue converts a log value into a value which can be encoded as json
+func logValue(v otellog.Value) any {
+	switch v.Kind() {
+	case otellog.KindBool:
+		return v.AsBool()
+	case otellog.KindFloat64:
+		return v.AsFloat64()
+	case otellog.KindInt64:
+		return v.AsInt64()
+	case otellog.KindString:
+		return v.AsString()
+	case otellog.KindBytes:
+		return v.AsBytes()
+	case otellog.KindSlice:
+		values := make([]any, 0, len(v.AsSlice()))
+		for _, item := range v.AsSlice() {
+			values = append(values, logValue(item))
+		}
+		return values
+	case otellog.KindMap:
+		values := make(map[string]any, len(v.AsMap()))
+		for _, kv := range v.AsMap() {
+			values[kv.Key] = logValue(kv.Value)
+		}
+		return values
+	}
+	return nil
+}
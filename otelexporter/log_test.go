@@ -0,0 +1,74 @@
+package otelexporter_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"github.com/timeplus-io/go-client/otelexporter"
+	"github.com/timeplus-io/go-client/timeplustest"
+)
+
+func TestLogExporter(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	exporter, err := otelexporter.NewLogExporter(server.Client(), otelexporter.StreamConfig{Stream: "app_logs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stream, ok := server.Stream("app_logs"); !ok || stream.EventTimeColumn != "timestamp" {
+		t.Fatalf("unexpected log stream %+v", stream)
+	}
+
+	provider := sdklog.NewLoggerProvider(
+		sdklog.WithResource(resource.NewSchemaless(semconv.ServiceName("checkout"))),
+		sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)),
+	)
+	logger := provider.Logger("shop")
+
+	var record otellog.Record
+	record.SetTimestamp(time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC))
+	record.SetSeverity(otellog.SeverityWarn)
+	record.SetSeverityText("WARN")
+	record.SetBody(otellog.StringValue("payment slow"))
+	record.AddAttributes(otellog.Int("attempt", 2), otellog.Map("card", otellog.String("brand", "visa")))
+	logger.Emit(context.Background(), record)
+
+	record.SetBody(otellog.MapValue(otellog.Bool("ok", true)))
+	logger.Emit(context.Background(), record)
+
+	// a record without timestamp is stored at its observed timestamp
+	record.SetTimestamp(time.Time{})
+	record.SetObservedTimestamp(time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC))
+	logger.Emit(context.Background(), record)
+
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := server.Rows("app_logs")
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 log records, got %v", rows)
+	}
+
+	row := rows[0]
+	if row["timestamp"] != "2024-01-02 03:04:05.000000006" || row["severity_number"] != float64(otellog.SeverityWarn) ||
+		row["severity_text"] != "WARN" || row["body"] != "payment slow" || row["service"] != "checkout" || row["trace_id"] != "" {
+		t.Errorf("unexpected log row %v", row)
+	}
+	attrs := row["attributes"].(map[string]any)
+	if attrs["attempt"] != float64(2) || attrs["card"].(map[string]any)["brand"] != "visa" {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+	if rows[1]["body"] != `{"ok":true}` {
+		t.Errorf("unexpected structured body %v", rows[1]["body"])
+	}
+	if rows[2]["timestamp"] != "2024-01-02 03:04:06.000000000" {
+		t.Errorf("expected the observed timestamp, got %v", rows[2]["timestamp"])
+	}
+}
@@ -0,0 +1,254 @@
+package otelexporter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/timeplus-io/go-client/metrics"
+)
+
+const DefaultMetricsName = "otel"
+
+// The tags and values of the metrics stream written by MetricExporter
+var (
+	MetricTags   = []string{"service", "kind", "unit", "temporality"}
+	MetricValues = []string{"value", "count", "sum", "min", "max"}
+)
+
+// The kinds of the exported metrics
+const (
+	KindGauge                = "gauge"
+	KindSum                  = "sum"
+	KindHistogram            = "histogram"
+	KindExponentialHistogram = "exponential_histogram"
+	KindSummary              = "summary"
+)
+
+type MetricExporterConfig struct {
+	// Name of the metrics written, the stream is named with the stream prefix of Options
+	Name string
+	// PushInterval is how often the metrics are flushed besides every Export
+	PushInterval time.Duration
+	Options      metrics.MetricsOptions
+	// TemporalitySelector and AggregationSelector default to the selectors of the SDK
+	TemporalitySelector sdkmetric.TemporalitySelector
+	AggregationSelector sdkmetric.AggregationSelector
+}
+
+// MetricExporter writes every data point as one row of a metrics stream. The scope name is stored
+// as namespace and the metric name as subsystem, the attributes of the data point and the resource,
+// the scope version, the start time and the histogram buckets are stored in the extra tags.
+type MetricExporter struct {
+	metrics *metrics.Metrics
+	config  MetricExporterConfig
+}
+
+var _ sdkmetric.Exporter = (*MetricExporter)(nil)
+
+func NewMetricExporter(client metrics.Client, config MetricExporterConfig) (*MetricExporter, error) {
+	if len(config.Name) == 0 {
+		config.Name = DefaultMetricsName
+	}
+	if config.PushInterval <= 0 {
+		config.PushInterval = 10 * time.Second
+	}
+	if config.TemporalitySelector == nil {
+		config.TemporalitySelector = sdkmetric.DefaultTemporalitySelector
+	}
+	if config.AggregationSelector == nil {
+		config.AggregationSelector = sdkmetric.DefaultAggregationSelector
+	}
+
+	m, err := metrics.NewMetricsWithOptions(config.Name, MetricTags, MetricValues, client, config.PushInterval, config.Options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otel metrics: %w", err)
+	}
+	return &MetricExporter{metrics: m, config: config}, nil
+}
+
+func (e *MetricExporter) Temporality(kind sdkmetric.InstrumentKind) metricdata.Temporality {
+	return e.config.TemporalitySelector(kind)
+}
+
+func (e *MetricExporter) Aggregation(kind sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return e.config.AggregationSelector(kind)
+}
+
+// Export records the data points and flushes them, a failed ingestion is returned to the SDK
+func (e *MetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	service := serviceName(rm.Resource)
+	res := resourceAttributes(rm.Resource)
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, metric := range sm.Metrics {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			w := &pointWriter{
+				metrics:  e.metrics,
+				service:  service,
+				resource: res,
+				scope:    sm.Scope.Name,
+				version:  sm.Scope.Version,
+				metric:   metric,
+			}
+			if err := w.write(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return e.metrics.FlushWithError()
+}
+
+func (e *MetricExporter) ForceFlush(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return e.metrics.FlushWithError()
+}
+
+func (e *MetricExporter) Shutdown(ctx context.Context) error {
+	return e.metrics.Close(ctx)
+}
+
+// pointWriter records the data points of one metric
+type pointWriter struct {
+	metrics  *metrics.Metrics
+	service  string
+	resource map[string]any
+	scope    string
+	version  string
+	metric   metricdata.Metrics
+}
+
+func (w *pointWriter) write() error {
+	switch data := w.metric.Data.(type) {
+	case metricdata.Gauge[int64]:
+		return writeNumbers(w, KindGauge, "", data.DataPoints, nil)
+	case metricdata.Gauge[float64]:
+		return writeNumbers(w, KindGauge, "", data.DataPoints, nil)
+	case metricdata.Sum[int64]:
+		return writeNumbers(w, KindSum, data.Temporality.String(), data.DataPoints, map[string]any{"monotonic": data.IsMonotonic})
+	case metricdata.Sum[float64]:
+		return writeNumbers(w, KindSum, data.Temporality.String(), data.DataPoints, map[string]any{"monotonic": data.IsMonotonic})
+	case metricdata.Histogram[int64]:
+		return writeHistogram(w, data)
+	case metricdata.Histogram[float64]:
+		return writeHistogram(w, data)
+	case metricdata.ExponentialHistogram[int64]:
+		return writeExponentialHistogram(w, data)
+	case metricdata.ExponentialHistogram[float64]:
+		return writeExponentialHistogram(w, data)
+	case metricdata.Summary:
+		return writeSummary(w, data)
+	}
+	return fmt.Errorf("unsupported data type %T of metric %s", w.metric.Data, w.metric.Name)
+}
+
+func (w *pointWriter) record(kind string, temporality string, timestamp time.Time, values metrics.Values, extraTags map[string]any) error {
+	labels := metrics.Labels{
+		"service":     w.service,
+		"kind":        kind,
+		"unit":        w.metric.Unit,
+		"temporality": temporality,
+	}
+
+	extraTags["resource"] = w.resource
+	if len(w.version) > 0 {
+		extraTags["scope_version"] = w.version
+	}
+
+	err := w.metrics.With(labels).Namespace(w.scope, w.metric.Name).RecordAtWithExtraTags(timestamp, values, extraTags)
+	if err != nil {
+		return fmt.Errorf("failed to record metric %s: %w", w.metric.Name, err)
+	}
+	return nil
+}
+
+func pointExtraTags(attrs map[string]any, start time.Time, extra map[string]any) map[string]any {
+	extraTags := map[string]any{"attributes": attrs}
+	if !start.IsZero() {
+		extraTags["start_time"] = start.UTC().Format(metrics.TimestampFormat)
+	}
+	for k, v := range extra {
+		extraTags[k] = v
+	}
+	return extraTags
+}
+
+func writeNumbers[N int64 | float64](w *pointWriter, kind string, temporality string, points []metricdata.DataPoint[N], extra map[string]any) error {
+	for _, point := range points {
+		extraTags := pointExtraTags(attributesToMap(point.Attributes.Iter()), point.StartTime, extra)
+		if err := w.record(kind, temporality, point.Time, metrics.Values{"value": float64(point.Value)}, extraTags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extremaValues[N int64 | float64](values metrics.Values, min metricdata.Extrema[N], max metricdata.Extrema[N]) {
+	if v, ok := min.Value(); ok {
+		values["min"] = float64(v)
+	}
+	if v, ok := max.Value(); ok {
+		values["max"] = float64(v)
+	}
+}
+
+func writeHistogram[N int64 | float64](w *pointWriter, data metricdata.Histogram[N]) error {
+	for _, point := range data.DataPoints {
+		values := metrics.Values{"count": float64(point.Count), "sum": float64(point.Sum)}
+		extremaValues(values, point.Min, point.Max)
+
+		extraTags := pointExtraTags(attributesToMap(point.Attributes.Iter()), point.StartTime, map[string]any{
+			"bounds":        point.Bounds,
+			"bucket_counts": point.BucketCounts,
+		})
+		if err := w.record(KindHistogram, data.Temporality.String(), point.Time, values, extraTags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeExponentialHistogram[N int64 | float64](w *pointWriter, data metricdata.ExponentialHistogram[N]) error {
+	for _, point := range data.DataPoints {
+		values := metrics.Values{"count": float64(point.Count), "sum": float64(point.Sum)}
+		extremaValues(values, point.Min, point.Max)
+
+		extraTags := pointExtraTags(attributesToMap(point.Attributes.Iter()), point.StartTime, map[string]any{
+			"scale":                  point.Scale,
+			"zero_count":             point.ZeroCount,
+			"positive_offset":        point.PositiveBucket.Offset,
+			"positive_bucket_counts": point.PositiveBucket.Counts,
+			"negative_offset":        point.NegativeBucket.Offset,
+			"negative_bucket_counts": point.NegativeBucket.Counts,
+		})
+		if err := w.record(KindExponentialHistogram, data.Temporality.String(), point.Time, values, extraTags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSummary(w *pointWriter, data metricdata.Summary) error {
+	for _, point := range data.DataPoints {
+		quantiles := make(map[string]float64, len(point.QuantileValues))
+		for _, q := range point.QuantileValues {
+			quantiles[fmt.Sprint(q.Quantile)] = q.Value
+		}
+
+		values := metrics.Values{"count": float64(point.Count), "sum": point.Sum}
+		extraTags := pointExtraTags(attributesToMap(point.Attributes.Iter()), point.StartTime, map[string]any{"quantiles": quantiles})
+		if err := w.record(KindSummary, "", point.Time, values, extraTags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
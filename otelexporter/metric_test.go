@@ -0,0 +1,103 @@
+package otelexporter_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"github.com/timeplus-io/go-client/otelexporter"
+	"github.com/timeplus-io/go-client/timeplustest"
+)
+
+func TestMetricExporter(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	exporter, err := otelexporter.NewMetricExporter(server.Client(), otelexporter.MetricExporterConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := resource.NewSchemaless(semconv.ServiceName("checkout"))
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
+	)
+	meter := provider.Meter("shop", metric.WithInstrumentationVersion("1.0"))
+
+	counter, _ := meter.Int64Counter("orders", metric.WithUnit("1"))
+	histogram, _ := meter.Float64Histogram("latency", metric.WithUnit("s"), metric.WithExplicitBucketBoundaries(0.1, 1))
+	ctx := context.Background()
+	counter.Add(ctx, 3, metric.WithAttributes(attribute.String("region", "eu")))
+	histogram.Record(ctx, 0.5)
+	histogram.Record(ctx, 2)
+
+	if err := provider.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := server.Stream("_tp_metric_otel"); !ok {
+		t.Fatalf("metrics stream not created")
+	}
+
+	rows := server.Rows("_tp_metric_otel")
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %v", rows)
+	}
+
+	bySubsystem := make(map[string]timeplustest.Row)
+	for _, row := range rows {
+		bySubsystem[row["subsystem"].(string)] = row
+	}
+
+	orders := bySubsystem["orders"]
+	if orders["namespace"] != "shop" || orders["service"] != "checkout" || orders["kind"] != otelexporter.KindSum ||
+		orders["temporality"] != "CumulativeTemporality" || orders["value"] != float64(3) {
+		t.Errorf("unexpected counter row %v", orders)
+	}
+	tags := orders["tags"].(map[string]any)
+	if tags["attributes"].(map[string]any)["region"] != "eu" || tags["scope_version"] != "1.0" || tags["monotonic"] != true {
+		t.Errorf("unexpected counter extra tags %v", tags)
+	}
+
+	latency := bySubsystem["latency"]
+	if latency["kind"] != otelexporter.KindHistogram || latency["count"] != float64(2) || latency["sum"] != 2.5 ||
+		latency["min"] != 0.5 || latency["max"] != float64(2) || latency["value"] != nil {
+		t.Errorf("unexpected histogram row %v", latency)
+	}
+	counts := latency["tags"].(map[string]any)["bucket_counts"].([]any)
+	if len(counts) != 3 || counts[0] != float64(0) || counts[1] != float64(1) || counts[2] != float64(1) {
+		t.Errorf("unexpected bucket counts %v", counts)
+	}
+}
+
+func TestMetricExporterFailedExport(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	exporter, err := otelexporter.NewMetricExporter(server.Client(), otelexporter.MetricExporterConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exporter.Shutdown(context.Background())
+
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	counter, _ := provider.Meter("shop").Int64Counter("orders")
+	counter.Add(context.Background(), 1)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+
+	server.InjectFault(timeplustest.Fault{Path: "/ingest", Status: http.StatusServiceUnavailable})
+	if err := exporter.Export(context.Background(), &rm); err == nil {
+		t.Errorf("expected the failed ingestion to be returned")
+	}
+}
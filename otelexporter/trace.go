@@ -0,0 +1,162 @@
+package otelexporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/timeplus-io/go-client/timeplus"
+)
+
+const DefaultSpanStream = "otel_spans"
+
+// SpanColumns is the schema of the span stream, events and links are stored as json arrays in string columns
+var SpanColumns = []timeplus.ColumnDef{
+	{Name: "start_time", Type: timestampColumnType},
+	{Name: "end_time", Type: timestampColumnType},
+	{Name: "duration_ns", Type: "int64"},
+	{Name: "trace_id", Type: "string"},
+	{Name: "span_id", Type: "string"},
+	{Name: "parent_span_id", Type: "string"},
+	{Name: "trace_state", Type: "string"},
+	{Name: "name", Type: "string"},
+	{Name: "kind", Type: "string"},
+	{Name: "status_code", Type: "string"},
+	{Name: "status_message", Type: "string"},
+	{Name: "service", Type: "string"},
+	{Name: "scope", Type: "string"},
+	{Name: "scope_version", Type: "string"},
+	{Name: "attributes", Type: "json"},
+	{Name: "resource", Type: "json"},
+	{Name: "events", Type: "string"},
+	{Name: "links", Type: "string"},
+}
+
+// SpanExporter inserts every batch of ended spans into the span stream, it is meant to be used
+// with a batch span processor so each insert carries many spans
+type SpanExporter struct {
+	client  timeplus.StreamWriter
+	config  StreamConfig
+	columns []string
+
+	lock     sync.Mutex
+	shutdown bool
+}
+
+var _ sdktrace.SpanExporter = (*SpanExporter)(nil)
+
+// NewSpanExporter creates the span stream unless it exists
+func NewSpanExporter(client timeplus.StreamWriter, config StreamConfig) (*SpanExporter, error) {
+	config = config.withDefaults(DefaultSpanStream)
+	if err := ensureStream(client, config, SpanColumns); err != nil {
+		return nil, err
+	}
+	return &SpanExporter{
+		client:  client,
+		config:  config,
+		columns: columnNames(SpanColumns),
+	}, nil
+}
+
+type spanEvent struct {
+	Name       string         `json:"name"`
+	Time       any            `json:"time"`
+	Attributes map[string]any `json:"attributes"`
+}
+
+type spanLink struct {
+	TraceID    string         `json:"trace_id"`
+	SpanID     string         `json:"span_id"`
+	Attributes map[string]any `json:"attributes"`
+}
+
+func (e *SpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.lock.Lock()
+	shutdown := e.shutdown
+	e.lock.Unlock()
+	if shutdown || len(spans) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	rows := make([][]any, len(spans))
+	for index, span := range spans {
+		rows[index] = spanRow(span)
+	}
+
+	payload := &timeplus.IngestPayload{
+		Stream: e.config.Stream,
+		Data: timeplus.IngestData{
+			Columns: e.columns,
+			Data:    rows,
+		},
+	}
+	if err := e.client.InsertData(payload); err != nil {
+		return fmt.Errorf("failed to export %d spans: %w", len(spans), err)
+	}
+	return nil
+}
+
+func (e *SpanExporter) Shutdown(ctx context.Context) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.shutdown = true
+	return nil
+}
+
+func spanRow(span sdktrace.ReadOnlySpan) []any {
+	spanContext := span.SpanContext()
+
+	parentSpanID := ""
+	if span.Parent().HasSpanID() {
+		parentSpanID = span.Parent().SpanID().String()
+	}
+
+	events := make([]spanEvent, len(span.Events()))
+	for index, event := range span.Events() {
+		events[index] = spanEvent{
+			Name:       event.Name,
+			Time:       formatTime(event.Time),
+			Attributes: keyValuesToMap(event.Attributes),
+		}
+	}
+	eventsJSON, _ := json.Marshal(events)
+
+	links := make([]spanLink, len(span.Links()))
+	for index, link := range span.Links() {
+		links[index] = spanLink{
+			TraceID:    link.SpanContext.TraceID().String(),
+			SpanID:     link.SpanContext.SpanID().String(),
+			Attributes: keyValuesToMap(link.Attributes),
+		}
+	}
+	linksJSON, _ := json.Marshal(links)
+
+	scope := span.InstrumentationScope()
+	return []any{
+		formatTime(span.StartTime()),
+		formatTime(span.EndTime()),
+		span.EndTime().Sub(span.StartTime()).Nanoseconds(),
+		spanContext.TraceID().String(),
+		spanContext.SpanID().String(),
+		parentSpanID,
+		spanContext.TraceState().String(),
+		span.Name(),
+		span.SpanKind().String(),
+		span.Status().Code.String(),
+		span.Status().Description,
+		serviceName(span.Resource()),
+		scope.Name,
+		scope.Version,
+		keyValuesToMap(span.Attributes()),
+		resourceAttributes(span.Resource()),
+		string(eventsJSON),
+		string(linksJSON),
+	}
+}
@@ -0,0 +1,71 @@
+package otelexporter_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/timeplus-io/go-client/otelexporter"
+	"github.com/timeplus-io/go-client/timeplustest"
+)
+
+func TestSpanExporter(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	exporter, err := otelexporter.NewSpanExporter(server.Client(), otelexporter.StreamConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream, ok := server.Stream(otelexporter.DefaultSpanStream)
+	if !ok || stream.EventTimeColumn != "start_time" || len(stream.Columns) != len(otelexporter.SpanColumns) {
+		t.Fatalf("unexpected span stream %+v", stream)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName("checkout"))),
+		sdktrace.WithSyncer(exporter),
+	)
+	tracer := provider.Tracer("shop")
+
+	ctx, parent := tracer.Start(context.Background(), "order", trace.WithSpanKind(trace.SpanKindServer))
+	_, child := tracer.Start(ctx, "charge", trace.WithAttributes(attribute.Int("amount", 42)))
+	child.AddEvent("retry")
+	child.SetStatus(codes.Error, "declined")
+	child.End()
+	parent.End()
+
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := server.Rows(otelexporter.DefaultSpanStream)
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 spans, got %v", rows)
+	}
+
+	charge, order := rows[0], rows[1]
+	if charge["name"] != "charge" || charge["parent_span_id"] != order["span_id"] || charge["trace_id"] != order["trace_id"] {
+		t.Errorf("unexpected span relation %v %v", charge, order)
+	}
+	if charge["status_code"] != "Error" || charge["status_message"] != "declined" || charge["service"] != "checkout" || charge["scope"] != "shop" {
+		t.Errorf("unexpected charge span %v", charge)
+	}
+	if charge["attributes"].(map[string]any)["amount"] != float64(42) {
+		t.Errorf("unexpected attributes %v", charge["attributes"])
+	}
+	var events []map[string]any
+	if err := json.Unmarshal([]byte(charge["events"].(string)), &events); err != nil || len(events) != 1 || events[0]["name"] != "retry" {
+		t.Errorf("unexpected events %v", charge["events"])
+	}
+	if order["kind"] != "server" || order["parent_span_id"] != "" || order["duration_ns"].(float64) < 0 {
+		t.Errorf("unexpected order span %v", order)
+	}
+}
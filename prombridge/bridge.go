@@ -0,0 +1,229 @@
+// Package prombridge writes Prometheus samples into a Timeplus metrics stream, either by scraping
+// an exposition endpoint or by accepting Prometheus remote write requests.
+//
+// All samples are written into one metrics stream, the Prometheus metric name is stored in the
+// subsystem column, the labels become the tags of the stream and the sample value is stored in
+// the value column. Labels seen later are added to the stream as new tags.
+package prombridge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/timeplus-io/go-client/metrics"
+)
+
+const ValueColumn = "value"
+const DefaultStream = "prometheus"
+const DefaultNamespace = "prometheus"
+const DefaultScrapeTimeout = 10 * time.Second
+const DefaultPushInterval = 10 * time.Second
+const DefaultMaxRequestBytes = 32 << 20
+
+// labelPrefix is added to label names which clash with the base columns of a metrics stream
+const labelPrefix = "label_"
+
+type Config struct {
+	// Stream is the name of the metrics all samples are written into, DefaultStream is used if empty
+	Stream string
+	// Namespace is written into the namespace column of every sample
+	Namespace string
+	// PushInterval is how often the metrics stream is flushed
+	PushInterval time.Duration
+	// Options controls the layout of the metrics stream created by the bridge
+	Options metrics.MetricsOptions
+	// ErrorHandler receives the errors of the background scraper and the samples which cannot be recorded,
+	// they are logged by default
+	ErrorHandler func(error)
+	// MaxRequestBytes limits the compressed and the decompressed size of a remote write request,
+	// DefaultMaxRequestBytes is used if 0
+	MaxRequestBytes int
+	// HTTPClient is used to scrape exposition endpoints, a client with DefaultScrapeTimeout is used if nil
+	HTTPClient *http.Client
+}
+
+// Sample is a single value of a Prometheus time series
+type Sample struct {
+	Name      string
+	Labels    map[string]string
+	Value     float64
+	Timestamp time.Time
+}
+
+type Bridge struct {
+	client metrics.Client
+	config Config
+
+	// lock is held while a sample is recorded and while the metrics is replaced to add tags
+	lock    sync.Mutex
+	metrics *metrics.Metrics
+	tags    []string
+}
+
+func NewBridge(client metrics.Client, config Config) *Bridge {
+	if len(config.Stream) == 0 {
+		config.Stream = DefaultStream
+	}
+	if len(config.Namespace) == 0 {
+		config.Namespace = DefaultNamespace
+	}
+	if config.PushInterval <= 0 {
+		config.PushInterval = DefaultPushInterval
+	}
+	if config.MaxRequestBytes <= 0 {
+		config.MaxRequestBytes = DefaultMaxRequestBytes
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: DefaultScrapeTimeout}
+	}
+	return &Bridge{
+		client: client,
+		config: config,
+	}
+}
+
+// Write records samples into the metrics stream, the stream is created on first use.
+// Samples without a finite value, such as the staleness markers of Prometheus, are skipped.
+// The stream is prepared for the labels of all samples before any sample is recorded, so a failed
+// write records nothing and can be retried without duplicates. Samples which cannot be recorded
+// are reported to the error handler instead, retrying them would not help.
+func (b *Bridge) Write(samples []Sample) error {
+	type labeledSample struct {
+		sample Sample
+		labels metrics.Labels
+	}
+
+	pending := make([]labeledSample, 0, len(samples))
+	union := make(metrics.Labels)
+	for _, sample := range samples {
+		if math.IsNaN(sample.Value) || math.IsInf(sample.Value, 0) {
+			continue
+		}
+
+		labels := make(metrics.Labels, len(sample.Labels))
+		for name, value := range sample.Labels {
+			if name == "__name__" {
+				continue
+			}
+			labels[b.columnName(name)] = value
+			union[b.columnName(name)] = ""
+		}
+		pending = append(pending, labeledSample{sample: sample, labels: labels})
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if err := b.prepare(union); err != nil {
+		return err
+	}
+
+	for _, p := range pending {
+		timestamp := p.sample.Timestamp
+		if timestamp.IsZero() {
+			timestamp = time.Now()
+		}
+
+		err := b.metrics.With(p.labels).
+			Namespace(b.config.Namespace, p.sample.Name).
+			RecordAt(timestamp, metrics.Values{ValueColumn: p.sample.Value})
+		if err != nil {
+			b.handleError(fmt.Errorf("failed to record sample of %s: %w", p.sample.Name, err))
+		}
+	}
+	return nil
+}
+
+// prepare makes sure the metrics covers all labels, it is called with the lock held
+func (b *Bridge) prepare(labels metrics.Labels) error {
+	if b.metrics != nil && containsAll(b.tags, labels) {
+		return nil
+	}
+
+	tags := append([]string{}, b.tags...)
+	for label := range labels {
+		if !contains(tags, label) {
+			tags = append(tags, label)
+		}
+	}
+	sort.Strings(tags)
+
+	// the pending observations are written with the old schema before the stream gets the new tags
+	if b.metrics != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), b.config.PushInterval)
+		err := b.metrics.Close(ctx)
+		cancel()
+		b.metrics = nil
+		if err != nil {
+			return err
+		}
+	}
+
+	m, err := metrics.NewMetricsWithOptions(b.config.Stream, tags, []string{ValueColumn}, b.client, b.config.PushInterval, b.config.Options)
+	if err != nil {
+		return fmt.Errorf("failed to create metrics %s: %w", b.config.Stream, err)
+	}
+	b.metrics = m
+	b.tags = tags
+	return nil
+}
+
+// columnName keeps the label names from clashing with the base columns of the metrics stream
+func (b *Bridge) columnName(label string) string {
+	columns := b.config.Options.Columns
+	reserved := []string{"timestamp", "namespace", "namepsace", "subsystem", "tags", ValueColumn,
+		columns.Timestamp, columns.Namespace, columns.Subsystem, columns.ExtraTags}
+	if contains(reserved, label) || strings.HasPrefix(label, "_tp_") {
+		return labelPrefix + label
+	}
+	return label
+}
+
+// Close flushes and closes the metrics written by the bridge
+func (b *Bridge) Close(ctx context.Context) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.metrics == nil {
+		return nil
+	}
+	err := b.metrics.Close(ctx)
+	b.metrics = nil
+	return err
+}
+
+func (b *Bridge) handleError(err error) {
+	if b.config.ErrorHandler != nil {
+		b.config.ErrorHandler(err)
+		return
+	}
+	log.Printf("prombridge: %s", err)
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAll(names []string, labels metrics.Labels) bool {
+	for label := range labels {
+		if !contains(names, label) {
+			return false
+		}
+	}
+	return true
+}
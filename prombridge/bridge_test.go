@@ -0,0 +1,223 @@
+package prombridge_test
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/timeplus-io/go-client/metrics"
+	"github.com/timeplus-io/go-client/prombridge"
+	"github.com/timeplus-io/go-client/timeplustest"
+)
+
+func encodeTimeseries(labels map[string]string, value float64, timestampMS int64) []byte {
+	var series []byte
+	for name, v := range labels {
+		var label []byte
+		label = protowire.AppendTag(label, 1, protowire.BytesType)
+		label = protowire.AppendString(label, name)
+		label = protowire.AppendTag(label, 2, protowire.BytesType)
+		label = protowire.AppendString(label, v)
+		series = protowire.AppendTag(series, 1, protowire.BytesType)
+		series = protowire.AppendBytes(series, label)
+	}
+
+	var sample []byte
+	sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(value))
+	sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, uint64(timestampMS))
+	series = protowire.AppendTag(series, 2, protowire.BytesType)
+	series = protowire.AppendBytes(series, sample)
+
+	var request []byte
+	request = protowire.AppendTag(request, 1, protowire.BytesType)
+	return protowire.AppendBytes(request, series)
+}
+
+func TestRemoteWrite(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	bridge := prombridge.NewBridge(server.Client(), prombridge.Config{PushInterval: time.Hour, Options: metrics.DefaultMetricsOptions()})
+
+	handler := bridge.RemoteWriteHandler()
+	send := func(body []byte) int {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/write", bytes.NewReader(snappy.Encode(nil, body)))
+		handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	first := encodeTimeseries(map[string]string{"__name__": "up", "job": "api"}, 1, 1700000000000)
+	if code := send(first); code != http.StatusNoContent {
+		t.Fatalf("unexpected status code %d", code)
+	}
+	// a new label adds a tag to the existing stream, a label named like a base column is prefixed
+	second := encodeTimeseries(map[string]string{"__name__": "scrape_duration_seconds", "job": "db", "instance": "a:1", "namespace": "x"}, 0.5, 1700000001000)
+	if code := send(second); code != http.StatusNoContent {
+		t.Fatalf("unexpected status code %d", code)
+	}
+	if code := send([]byte("not snappy")); code != http.StatusBadRequest {
+		t.Errorf("expected bad request for invalid body, got %d", code)
+	}
+
+	if err := bridge.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// all metric names share one stream and are told apart by the subsystem
+	if len(server.Streams()) != 1 {
+		t.Fatalf("expected a single stream, got %v", server.Streams())
+	}
+	rows := server.Rows("_tp_metric_prometheus")
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %v", rows)
+	}
+	if rows[0]["job"] != "api" || rows[0]["value"] != float64(1) || rows[0]["subsystem"] != "up" || rows[0]["namespace"] != "prometheus" {
+		t.Errorf("unexpected first row %v", rows[0])
+	}
+	if rows[1]["job"] != "db" || rows[1]["instance"] != "a:1" || rows[1]["label_namespace"] != "x" ||
+		rows[1]["value"] != 0.5 || rows[1]["subsystem"] != "scrape_duration_seconds" {
+		t.Errorf("unexpected second row %v", rows[1])
+	}
+
+	found := false
+	for _, statement := range server.Statements() {
+		if strings.Contains(statement, "ADD COLUMN `instance` string") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected the instance column to be added, got %v", server.Statements())
+	}
+}
+
+func TestScrape(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	bridge := prombridge.NewBridge(server.Client(), prombridge.Config{Stream: "node", Namespace: "node", PushInterval: time.Hour})
+
+	exporter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`# TYPE requests_total counter
+requests_total{code="200"} 10
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 2
+latency_seconds_bucket{le="1"} 3
+latency_seconds_bucket{le="+Inf"} 4
+latency_seconds_sum 6.5
+latency_seconds_count 4
+`))
+	}))
+	defer exporter.Close()
+
+	if err := bridge.Scrape(context.Background(), exporter.URL); err != nil {
+		t.Fatal(err)
+	}
+	if err := bridge.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	bySubsystem := make(map[string][]timeplustest.Row)
+	for _, row := range server.Rows("_tp_metric_node") {
+		subsystem, _ := row["subsystem"].(string)
+		bySubsystem[subsystem] = append(bySubsystem[subsystem], row)
+	}
+	requests := bySubsystem["requests_total"]
+	if len(requests) != 1 || requests[0]["code"] != "200" || requests[0]["value"] != float64(10) || requests[0]["namespace"] != "node" {
+		t.Errorf("unexpected counter rows %v", requests)
+	}
+	if buckets := bySubsystem["latency_seconds_bucket"]; len(buckets) != 3 {
+		t.Errorf("expected 3 bucket rows, got %v", buckets)
+	}
+	if count := bySubsystem["latency_seconds_count"]; len(count) != 1 || count[0]["value"] != float64(4) {
+		t.Errorf("unexpected count rows %v", count)
+	}
+}
+
+func TestScrapeTimeout(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	client := &http.Client{Timeout: 50 * time.Millisecond}
+	bridge := prombridge.NewBridge(server.Client(), prombridge.Config{PushInterval: time.Hour, HTTPClient: client})
+	defer bridge.Close(context.Background())
+
+	release := make(chan struct{})
+	exporter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer exporter.Close()
+	defer close(release)
+
+	if err := bridge.Scrape(context.Background(), exporter.URL); err == nil {
+		t.Fatal("expected the scrape to time out")
+	}
+}
+
+func TestRemoteWriteLimits(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	bridge := prombridge.NewBridge(server.Client(), prombridge.Config{PushInterval: time.Hour, MaxRequestBytes: 1024})
+	defer bridge.Close(context.Background())
+	handler := bridge.RemoteWriteHandler()
+
+	send := func(method string, body []byte) int {
+		req := httptest.NewRequest(method, "/write", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := send(http.MethodGet, nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("expected GET to be rejected, got %d", code)
+	}
+	if code := send(http.MethodPost, bytes.Repeat([]byte{1}, 2048)); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected a large request to be rejected, got %d", code)
+	}
+	// a small request decompressing beyond the limit
+	if code := send(http.MethodPost, snappy.Encode(nil, make([]byte, 4096))); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected a large decompressed request to be rejected, got %d", code)
+	}
+}
+
+func TestDecodeWriteRequestWithoutTimestamp(t *testing.T) {
+	samples, err := prombridge.DecodeWriteRequest(encodeTimeseries(map[string]string{"__name__": "up"}, 1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || !samples[0].Timestamp.IsZero() {
+		t.Errorf("expected a sample without timestamp, got %v", samples)
+	}
+}
+
+func TestWriteFailsWithoutRecording(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	bridge := prombridge.NewBridge(server.Client(), prombridge.Config{PushInterval: time.Hour})
+
+	samples := []prombridge.Sample{
+		{Name: "up", Labels: map[string]string{"job": "api"}, Value: 1},
+		{Name: "requests_total", Labels: map[string]string{"code": "200"}, Value: 10},
+	}
+	server.InjectFault(timeplustest.Fault{Method: http.MethodPost, Path: "/streams", Status: http.StatusServiceUnavailable, Times: 1})
+	if err := bridge.Write(samples); err == nil {
+		t.Fatal("expected the failed stream creation to be returned")
+	}
+	if err := bridge.Write(samples); err != nil {
+		t.Fatalf("retried write failed: %s", err)
+	}
+	if err := bridge.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if rows := server.Rows("_tp_metric_prometheus"); len(rows) != 2 {
+		t.Errorf("expected the samples recorded once, got %v", rows)
+	}
+}
@@ -0,0 +1,211 @@
+package prombridge
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// RemoteWriteHandler accepts Prometheus remote write requests, which are snappy compressed WriteRequest protobufs.
+// The compressed and the decompressed request are limited to Config.MaxRequestBytes.
+func (b *Bridge) RemoteWriteHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "remote write requires POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		compressed, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(b.config.MaxRequestBytes)))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		size, err := snappy.DecodedLen(compressed)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to decompress request: %s", err), http.StatusBadRequest)
+			return
+		}
+		if size > b.config.MaxRequestBytes {
+			http.Error(w, fmt.Sprintf("decompressed request of %d bytes exceeds the limit of %d bytes", size, b.config.MaxRequestBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		data, err := snappy.Decode(nil, compressed)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to decompress request: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		samples, err := DecodeWriteRequest(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := b.Write(samples); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// The field numbers of the remote write protobuf messages
+const (
+	writeRequestTimeseries = 1
+	timeseriesLabels       = 1
+	timeseriesSamples      = 2
+	labelName              = 1
+	labelValue             = 2
+	sampleValue            = 1
+	sampleTimestamp        = 2
+)
+
+// DecodeWriteRequest decodes the uncompressed protobuf of a remote write request into samples,
+// the metric name is taken from the __name__ label. Exemplars, histograms and metadata are ignored.
+func DecodeWriteRequest(data []byte) ([]Sample, error) {
+	samples := make([]Sample, 0)
+	err := walkFields(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if num != writeRequestTimeseries || typ != protowire.BytesType {
+			return nil
+		}
+		series, err := decodeTimeseries(value)
+		if err != nil {
+			return err
+		}
+		samples = append(samples, series...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote write request: %w", err)
+	}
+	return samples, nil
+}
+
+func decodeTimeseries(data []byte) ([]Sample, error) {
+	labels := make(map[string]string)
+	type point struct {
+		value     float64
+		timestamp int64
+	}
+	points := make([]point, 0, 1)
+
+	err := walkFields(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case timeseriesLabels:
+			var name, labelVal string
+			err := walkFields(value, func(num protowire.Number, typ protowire.Type, value []byte) error {
+				switch {
+				case num == labelName && typ == protowire.BytesType:
+					name = string(value)
+				case num == labelValue && typ == protowire.BytesType:
+					labelVal = string(value)
+				}
+				return nil
+			})
+			labels[name] = labelVal
+			return err
+		case timeseriesSamples:
+			var p point
+			err := walkFields(value, func(num protowire.Number, typ protowire.Type, value []byte) error {
+				switch {
+				case num == sampleValue && typ == protowire.Fixed64Type:
+					v, _ := protowire.ConsumeFixed64(value)
+					p.value = math.Float64frombits(v)
+				case num == sampleTimestamp && typ == protowire.VarintType:
+					v, _ := protowire.ConsumeVarint(value)
+					p.timestamp = int64(v)
+				}
+				return nil
+			})
+			points = append(points, p)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	name, ok := labels["__name__"]
+	if !ok {
+		return nil, fmt.Errorf("time series without __name__ label")
+	}
+	delete(labels, "__name__")
+
+	samples := make([]Sample, len(points))
+	for index, p := range points {
+		samples[index] = Sample{
+			Name:   name,
+			Labels: labels,
+			Value:  p.value,
+		}
+		// a sample without timestamp is recorded at the time it is written
+		if p.timestamp != 0 {
+			samples[index].Timestamp = time.UnixMilli(p.timestamp)
+		}
+	}
+	return samples, nil
+}
+
+// walkFields calls fn with the raw value of every field of a protobuf message,
+// fixed64 values are passed as their 8 bytes and varints as their encoding
+func walkFields(data []byte, fn func(num protowire.Number, typ protowire.Type, value []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		var value []byte
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			value, data = v, data[n:]
+		case protowire.Fixed64Type:
+			_, n := protowire.ConsumeFixed64(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			value, data = data[:n], data[n:]
+		case protowire.VarintType:
+			_, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			value, data = data[:n], data[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+			continue
+		}
+
+		if err := fn(num, typ, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
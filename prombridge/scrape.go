@@ -0,0 +1,126 @@
+package prombridge
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Scrape reads the text exposition of url and writes all samples
+func (b *Bridge) Scrape(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/plain;version=0.0.4")
+
+	res, err := b.config.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to scrape %s: %w", url, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 || res.StatusCode < 200 {
+		return fmt.Errorf("failed to scrape %s: status code %d", url, res.StatusCode)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to parse metrics of %s: %w", url, err)
+	}
+
+	return b.Write(FamiliesToSamples(families, time.Now()))
+}
+
+// RunScraper scrapes url every interval until ctx is done, failed scrapes are reported to the error handler
+func (b *Bridge) RunScraper(ctx context.Context, url string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.Scrape(ctx, url); err != nil && ctx.Err() == nil {
+			b.handleError(err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// FamiliesToSamples flattens metric families the way Prometheus stores them, histograms become
+// the _bucket, _sum and _count series and summaries the quantile, _sum and _count series.
+// now is used for the metrics exposed without timestamp.
+func FamiliesToSamples(families map[string]*dto.MetricFamily, now time.Time) []Sample {
+	samples := make([]Sample, 0)
+	for name, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string, len(metric.GetLabel()))
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+
+			timestamp := now
+			if metric.TimestampMs != nil {
+				timestamp = time.UnixMilli(metric.GetTimestampMs())
+			}
+
+			add := func(name string, extraName string, extraValue string, value float64) {
+				sampleLabels := labels
+				if len(extraName) > 0 {
+					sampleLabels = make(map[string]string, len(labels)+1)
+					for k, v := range labels {
+						sampleLabels[k] = v
+					}
+					sampleLabels[extraName] = extraValue
+				}
+				samples = append(samples, Sample{Name: name, Labels: sampleLabels, Value: value, Timestamp: timestamp})
+			}
+
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				add(name, "", "", metric.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add(name, "", "", metric.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add(name, "", "", metric.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				summary := metric.GetSummary()
+				for _, q := range summary.GetQuantile() {
+					add(name, "quantile", formatFloat(q.GetQuantile()), q.GetValue())
+				}
+				add(name+"_sum", "", "", summary.GetSampleSum())
+				add(name+"_count", "", "", float64(summary.GetSampleCount()))
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				histogram := metric.GetHistogram()
+				for _, bucket := range histogram.GetBucket() {
+					add(name+"_bucket", "le", formatFloat(bucket.GetUpperBound()), float64(bucket.GetCumulativeCount()))
+				}
+				// the text format exposes the +Inf bucket, other sources may leave it implicit
+				buckets := histogram.GetBucket()
+				if len(buckets) == 0 || !math.IsInf(buckets[len(buckets)-1].GetUpperBound(), 1) {
+					add(name+"_bucket", "le", "+Inf", float64(histogram.GetSampleCount()))
+				}
+				add(name+"_sum", "", "", histogram.GetSampleSum())
+				add(name+"_count", "", "", float64(histogram.GetSampleCount()))
+			}
+		}
+	}
+	return samples
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
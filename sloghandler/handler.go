@@ -0,0 +1,260 @@
+// Package sloghandler provides a log/slog handler writing the log records into a Timeplus stream.
+//
+// The records are buffered and inserted in batches by a background writer. When the buffer
+// is full the record is dropped, so logging never blocks the caller on Timeplus.
+package sloghandler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+
+	"github.com/timeplus-io/go-client/metrics"
+	"github.com/timeplus-io/go-client/timeplus"
+)
+
+const DefaultStream = "app_logs"
+const DefaultBufferSize = 10000
+const DefaultBatchSize = 1000
+const DefaultFlushInterval = time.Second
+
+// Columns is the schema of the log stream, the attributes and groups are stored as nested json
+var Columns = []timeplus.ColumnDef{
+	{Name: "time", Type: metrics.TimestampColumnType},
+	{Name: "level", Type: "string"},
+	{Name: "level_number", Type: "int32"},
+	{Name: "message", Type: "string"},
+	{Name: "source", Type: "string"},
+	{Name: "attrs", Type: "json"},
+}
+
+type HandlerOptions struct {
+	// Level is the minimum level written, slog.LevelInfo is used if nil
+	Level slog.Leveler
+	// AddSource writes the file and line of the log call into the source column
+	AddSource bool
+
+	// Stream is created with the TTL and retention below unless it exists,
+	// metrics.NoTTL and metrics.NoRetention disable them
+	Stream                 string
+	TTLExpression          string
+	LogStoreRetentionBytes int
+	LogStoreRetentionMS    int
+
+	// BufferSize is the number of records kept for the writer, records beyond are dropped
+	BufferSize int
+	// BatchSize and FlushInterval control when the buffered records are inserted
+	BatchSize     int
+	FlushInterval time.Duration
+	// ErrorHandler receives the failed inserts, the records of a failed insert are dropped
+	ErrorHandler func(error)
+}
+
+func (o HandlerOptions) withDefaults() HandlerOptions {
+	if o.Level == nil {
+		o.Level = slog.LevelInfo
+	}
+	if len(o.Stream) == 0 {
+		o.Stream = DefaultStream
+	}
+	if len(o.TTLExpression) == 0 {
+		o.TTLExpression = metrics.DefaultTTL
+	}
+	if o.LogStoreRetentionBytes == 0 {
+		o.LogStoreRetentionBytes = metrics.DefaultLogStoreRetentionBytes
+	}
+	if o.LogStoreRetentionMS == 0 {
+		o.LogStoreRetentionMS = metrics.DefaultLogStoreRetentionMS
+	}
+	if o.BufferSize <= 0 {
+		o.BufferSize = DefaultBufferSize
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = DefaultBatchSize
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = DefaultFlushInterval
+	}
+	return o
+}
+
+// Handler is a slog.Handler, the handlers derived by WithAttrs and WithGroup share the writer of their parent
+type Handler struct {
+	writer *writer
+	opts   HandlerOptions
+	groups []string
+	attrs  []groupedAttrs
+}
+
+// groupedAttrs are the attributes added by WithAttrs within the groups opened before
+type groupedAttrs struct {
+	groups []string
+	attrs  []slog.Attr
+}
+
+var _ slog.Handler = (*Handler)(nil)
+
+// NewHandler creates the log stream unless it exists and starts the writer, Close stops it
+func NewHandler(client timeplus.StreamWriter, opts HandlerOptions) (*Handler, error) {
+	opts = opts.withDefaults()
+
+	if !client.ExistStream(opts.Stream) {
+		streamDef := timeplus.StreamDef{
+			Name:            opts.Stream,
+			Columns:         Columns,
+			EventTimeColumn: Columns[0].Name,
+		}
+		metrics.SetRetention(&streamDef, opts.TTLExpression, opts.LogStoreRetentionBytes, opts.LogStoreRetentionMS)
+		if err := client.CreateStream(streamDef); err != nil {
+			return nil, fmt.Errorf("failed to create log stream %s: %w", opts.Stream, err)
+		}
+	}
+
+	return &Handler{
+		writer: newWriter(client, opts),
+		opts:   opts,
+	}, nil
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.opts.Level.Level()
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := make(map[string]any)
+	for _, grouped := range h.attrs {
+		addAttrs(attrs, grouped.groups, grouped.attrs)
+	}
+	if r.NumAttrs() > 0 {
+		recordAttrs := make([]slog.Attr, 0, r.NumAttrs())
+		r.Attrs(func(a slog.Attr) bool {
+			recordAttrs = append(recordAttrs, a)
+			return true
+		})
+		addAttrs(attrs, h.groups, recordAttrs)
+	}
+
+	source := ""
+	if h.opts.AddSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		source = fmt.Sprintf("%s:%d", frame.File, frame.Line)
+	}
+
+	timestamp := r.Time
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	h.writer.write([]any{
+		timestamp.UTC().Format(metrics.TimestampFormat),
+		r.Level.String(),
+		int32(r.Level),
+		r.Message,
+		source,
+		attrs,
+	})
+	return nil
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	copied := *h
+	copied.attrs = append(h.attrs[:len(h.attrs):len(h.attrs)], groupedAttrs{groups: h.groups, attrs: attrs})
+	return &copied
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if len(name) == 0 {
+		return h
+	}
+	copied := *h
+	copied.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &copied
+}
+
+// Dropped returns the number of records dropped because the buffer was full or the insert failed
+func (h *Handler) Dropped() uint64 {
+	return h.writer.dropped.Load()
+}
+
+// Close inserts the buffered records and stops the writer, records handled afterwards are dropped
+func (h *Handler) Close(ctx context.Context) error {
+	return h.writer.close(ctx)
+}
+
+// addAttrs adds attrs into the nested map of groups, the groups are only created when they get an attribute
+func addAttrs(root map[string]any, groups []string, attrs []slog.Attr) {
+	target := make(map[string]any)
+	for _, a := range attrs {
+		addAttr(target, a)
+	}
+	if len(target) == 0 {
+		return
+	}
+
+	for _, group := range groups {
+		child, ok := root[group].(map[string]any)
+		if !ok {
+			child = make(map[string]any)
+			root[group] = child
+		}
+		root = child
+	}
+	for k, v := range target {
+		root[k] = v
+	}
+}
+
+func addAttr(m map[string]any, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		if len(group) == 0 {
+			return
+		}
+		// an inline group adds its attributes to the enclosing group
+		target := m
+		if len(a.Key) > 0 {
+			child, ok := m[a.Key].(map[string]any)
+			if !ok {
+				child = make(map[string]any)
+				m[a.Key] = child
+			}
+			target = child
+		}
+		for _, ga := range group {
+			addAttr(target, ga)
+		}
+		return
+	}
+	m[a.Key] = attrValue(a.Value)
+}
+
+func attrValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindTime:
+		return v.Time().UTC().Format(time.RFC3339Nano)
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindAny:
+		value := v.Any()
+		if err, ok := value.(error); ok {
+			return err.Error()
+		}
+		// the values which cannot be encoded would fail the whole batch
+		if _, err := json.Marshal(value); err != nil {
+			return fmt.Sprint(value)
+		}
+		return value
+	}
+	return v.Any()
+}
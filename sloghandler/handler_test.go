@@ -0,0 +1,109 @@
+package sloghandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timeplus-io/go-client/sloghandler"
+	"github.com/timeplus-io/go-client/timeplustest"
+)
+
+func TestHandler(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	handler, err := sloghandler.NewHandler(server.Client(), sloghandler.HandlerOptions{Level: slog.LevelDebug, AddSource: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	streams := server.Streams()
+	if len(streams) != 1 || streams[0].Name != sloghandler.DefaultStream || streams[0].TTLExpression == "" {
+		t.Fatalf("unexpected streams %+v", streams)
+	}
+
+	logger := slog.New(handler).With("service", "checkout").WithGroup("request").With("id", 7)
+	logger.Warn("slow payment", "took", time.Second, slog.Group("card", "brand", "visa"), "err", errors.New("timeout"))
+	logger.WithGroup("empty").Debug("debug message")
+	slog.New(handler).Log(context.Background(), slog.LevelDebug-1, "below level")
+
+	if err := handler.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := server.Rows(sloghandler.DefaultStream)
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %v", rows)
+	}
+
+	row := rows[0]
+	if row["level"] != "WARN" || row["level_number"] != float64(slog.LevelWarn) || row["message"] != "slow payment" ||
+		!strings.Contains(row["source"].(string), "handler_test.go:") {
+		t.Errorf("unexpected row %v", row)
+	}
+	attrs, _ := json.Marshal(row["attrs"])
+	expected := `{"request":{"card":{"brand":"visa"},"err":"timeout","id":7,"took":"1s"},"service":"checkout"}`
+	if string(attrs) != expected {
+		t.Errorf("unexpected attrs %s", attrs)
+	}
+
+	// a group without attributes is left out
+	attrs, _ = json.Marshal(rows[1]["attrs"])
+	if string(attrs) != `{"request":{"id":7},"service":"checkout"}` {
+		t.Errorf("unexpected attrs of empty group %s", attrs)
+	}
+}
+
+func TestHandlerDropsOnOverflow(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	handler, err := sloghandler.NewHandler(server.Client(), sloghandler.HandlerOptions{BufferSize: 2, BatchSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(handler)
+
+	// the first record blocks the writer on the slow server, two records fit into the buffer
+	server.InjectFault(timeplustest.Fault{Path: "/ingest", Delay: 500 * time.Millisecond, Times: 1})
+	logger.Info("first")
+	waitForIngest(t, server)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		logger.Info("queued")
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("logging blocked on a slow server")
+	}
+	if handler.Dropped() != 3 {
+		t.Errorf("expected 3 dropped records, got %d", handler.Dropped())
+	}
+
+	if err := handler.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if rows := server.Rows(sloghandler.DefaultStream); len(rows) != 3 {
+		t.Errorf("expected 3 rows, got %d", len(rows))
+	}
+
+	logger.Info("after close")
+	if handler.Dropped() != 4 {
+		t.Errorf("expected records after close to be dropped, got %d", handler.Dropped())
+	}
+}
+
+// waitForIngest waits until the server received an ingest request
+func waitForIngest(t *testing.T, server *timeplustest.Server) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, req := range server.Requests() {
+			if strings.Contains(req.Path, "/ingest") {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("no ingest request received")
+}
@@ -0,0 +1,131 @@
+package sloghandler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/timeplus-io/go-client/timeplus"
+)
+
+// writer inserts the rows written into it in batches from a background goroutine
+type writer struct {
+	client  timeplus.StreamWriter
+	opts    HandlerOptions
+	columns []string
+
+	rows    chan []any
+	dropped atomic.Uint64
+
+	lock    sync.RWMutex
+	closed  bool
+	stopped chan struct{}
+}
+
+func newWriter(client timeplus.StreamWriter, opts HandlerOptions) *writer {
+	columns := make([]string, len(Columns))
+	for index, col := range Columns {
+		columns[index] = col.Name
+	}
+
+	w := &writer{
+		client:  client,
+		opts:    opts,
+		columns: columns,
+		rows:    make(chan []any, opts.BufferSize),
+		stopped: make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// write never blocks, the row is dropped if the buffer is full or the writer is closed
+func (w *writer) write(row []any) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	if w.closed {
+		w.dropped.Add(1)
+		return
+	}
+
+	select {
+	case w.rows <- row:
+	default:
+		w.dropped.Add(1)
+	}
+}
+
+func (w *writer) run() {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([][]any, 0, w.opts.BatchSize)
+	for {
+		select {
+		case row, ok := <-w.rows:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			batch = append(batch, row)
+			if len(batch) >= w.opts.BatchSize {
+				w.flush(batch)
+				batch = make([][]any, 0, w.opts.BatchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.flush(batch)
+				batch = make([][]any, 0, w.opts.BatchSize)
+			}
+		}
+	}
+}
+
+func (w *writer) flush(batch [][]any) {
+	if len(batch) == 0 {
+		return
+	}
+
+	payload := &timeplus.IngestPayload{
+		Stream: w.opts.Stream,
+		Data: timeplus.IngestData{
+			Columns: w.columns,
+			Data:    batch,
+		},
+	}
+	if err := w.client.InsertData(payload); err != nil {
+		w.dropped.Add(uint64(len(batch)))
+		w.handleError(fmt.Errorf("failed to write %d log records: %w", len(batch), err))
+	}
+}
+
+// handleError writes to stderr directly, the log package may be routed into the handler by slog.SetDefault
+func (w *writer) handleError(err error) {
+	if w.opts.ErrorHandler != nil {
+		w.opts.ErrorHandler(err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "sloghandler: %s\n", err)
+}
+
+func (w *writer) close(ctx context.Context) error {
+	w.lock.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.rows)
+	}
+	w.lock.Unlock()
+
+	select {
+	case <-w.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
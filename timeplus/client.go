@@ -1,13 +1,13 @@
 package timeplus
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"strings"
+	"sync/atomic"
 
 	"github.com/reactivex/rxgo/v2"
 
@@ -27,6 +27,7 @@ type ColumnDef struct {
 
 type StreamDef struct {
 	Name                   string      `json:"name"`
+	Description            string      `json:"description,omitempty"`
 	Columns                []ColumnDef `json:"columns"`
 	EventTimeColumn        string      `json:"event_time_column,omitempty"`
 	EventTimeZone          string      `json:"event_time_timezone,omitempty"`
@@ -59,6 +60,8 @@ type Query struct {
 	Policy      BatchingPolicy `json:"batching_policy,omitempty"`
 }
 
+// BatchingPolicy asks the server to send the query result in batches of up to Count rows or every TimeMS
+// milliseconds, the client delivers every batch as sent by the server and does not batch again
 type BatchingPolicy struct {
 	Count  int `json:"count,omitempty"`
 	TimeMS int `json:"time_ms,omitempty"`
@@ -94,6 +97,17 @@ type QueryResultStream struct {
 	Metadata     *QueryInfo
 	ResultStream rxgo.Observable
 	Cancel       func()
+	// latest is the metadata of the query reissued by a resumable query
+	latest atomic.Pointer[QueryInfo]
+}
+
+// LatestMetadata returns the QueryInfo of the running query, which is the query reissued by the last
+// reconnect of a resumable query and Metadata otherwise
+func (r *QueryResultStream) LatestMetadata() *QueryInfo {
+	if latest := r.latest.Load(); latest != nil {
+		return latest
+	}
+	return r.Metadata
 }
 
 type QueryStat struct {
@@ -119,26 +133,78 @@ type TimeplusClient struct {
 	apikey  string
 	tenant  string
 	client  *http.Client
+	// streamClient is shared by the streaming queries
+	streamClient *http.Client
+	// ctx is set by WithContext, nil is the background context
+	ctx context.Context
 }
 
 func NewCient(address string, tenant string, apikey string) *TimeplusClient {
-	return &TimeplusClient{
-		address: address,
-		apikey:  apikey,
-		tenant:  tenant,
-		client:  utils.NewDefaultHttpClient(),
-	}
+	return NewCientWithHttpConfig(address, tenant, apikey, utils.NewDefaultHTTPClientConfig())
+}
+
+// NewCientWithAuthenticator authenticates the requests with auth instead of an api key, see utils.Authenticator
+func NewCientWithAuthenticator(address string, tenant string, auth utils.Authenticator) *TimeplusClient {
+	config := utils.NewDefaultHTTPClientConfig()
+	config.Authenticator = auth
+	return NewCientWithHttpConfig(address, tenant, "", config)
 }
 
+// NewCientWithHttpConfig creates a client of address, which is either a http(s) url or a unix:// socket path
 func NewCientWithHttpConfig(address string, tenant string, apikey string, config *utils.HTTPClientConfig) *TimeplusClient {
+	address, resolved := utils.ResolveAddress(address, *config)
 	return &TimeplusClient{
-		address: address,
-		apikey:  apikey,
-		tenant:  tenant,
-		client:  utils.NewHttpClient(*config),
+		address:      address,
+		apikey:       apikey,
+		tenant:       tenant,
+		client:       utils.NewHttpClient(resolved),
+		streamClient: utils.NewStreamingHttpClient(resolved),
 	}
 }
 
+// NewCientWithCheckedHttpConfig is NewCientWithHttpConfig failing on a config which cannot be loaded,
+// such as a missing CA file, instead of failing every request
+func NewCientWithCheckedHttpConfig(address string, tenant string, apikey string, config *utils.HTTPClientConfig) (*TimeplusClient, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return NewCientWithHttpConfig(address, tenant, apikey, config), nil
+}
+
+// NewMultiEndpointCient creates a client spreading its requests over the endpoints of pool, see utils.EndpointPool
+func NewMultiEndpointCient(pool *utils.EndpointPool, tenant string, apikey string, config *utils.HTTPClientConfig) *TimeplusClient {
+	withPool := *config
+	withPool.Endpoints = pool
+	return NewCientWithHttpConfig(utils.EndpointPoolAddress, tenant, apikey, &withPool)
+}
+
+// WithContext returns a copy of the client sending its requests with ctx, which cancels them and links
+// them to the trace of the caller. The streaming queries of the copy are cancelled with ctx as well.
+func (s *TimeplusClient) WithContext(ctx context.Context) *TimeplusClient {
+	copied := *s
+	copied.ctx = ctx
+	return &copied
+}
+
+func (s *TimeplusClient) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+func (s *TimeplusClient) headers() map[string]string {
+	headers := make(map[string]string)
+	if len(s.apikey) > 0 {
+		headers["X-Api-key"] = s.apikey
+	}
+	return headers
+}
+
+func (s *TimeplusClient) request(method string, url string, payload interface{}) (int, []byte, error) {
+	return utils.HttpRequestWithContext(s.context(), method, url, payload, s.client, s.headers())
+}
+
 func (s *TimeplusClient) baseUrl() string {
 	if len(s.tenant) == 0 {
 		return fmt.Sprintf("%s/api/%s", s.address, APIVersion)
@@ -149,7 +215,7 @@ func (s *TimeplusClient) baseUrl() string {
 
 func (s *TimeplusClient) CreateStream(streamDef StreamDef) error {
 	url := fmt.Sprintf("%s/streams", s.baseUrl())
-	_, _, err := utils.HttpRequestWithAPIKey(http.MethodPost, url, streamDef, s.client, s.apikey)
+	_, _, err := s.request(http.MethodPost, url, streamDef)
 	if err != nil {
 		return fmt.Errorf("failed to create stream %s: %w", streamDef.Name, err)
 	}
@@ -158,7 +224,7 @@ func (s *TimeplusClient) CreateStream(streamDef StreamDef) error {
 
 func (s *TimeplusClient) DeleteStream(streamName string) error {
 	url := fmt.Sprintf("%s/streams/%s", s.baseUrl(), streamName)
-	_, _, err := utils.HttpRequestWithAPIKey(http.MethodDelete, url, nil, s.client, s.apikey)
+	_, _, err := s.request(http.MethodDelete, url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to delete stream %s: %w", streamName, err)
 	}
@@ -197,7 +263,7 @@ func (s *TimeplusClient) GetStream(name string) (*StreamDef, error) {
 
 func (s *TimeplusClient) ListStream() ([]StreamDef, error) {
 	url := fmt.Sprintf("%s/streams", s.baseUrl())
-	_, respBody, err := utils.HttpRequestWithAPIKey(http.MethodGet, url, nil, s.client, s.apikey)
+	_, respBody, err := s.request(http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list stream : %w", err)
 	}
@@ -210,7 +276,7 @@ func (s *TimeplusClient) ListStream() ([]StreamDef, error) {
 
 func (s *TimeplusClient) CreateView(view View) error {
 	url := fmt.Sprintf("%s/views", s.baseUrl())
-	_, _, err := utils.HttpRequestWithAPIKey(http.MethodPost, url, view, s.client, s.apikey)
+	_, _, err := s.request(http.MethodPost, url, view)
 	if err != nil {
 		return fmt.Errorf("failed to create view %s: %w", view.Name, err)
 	}
@@ -219,7 +285,7 @@ func (s *TimeplusClient) CreateView(view View) error {
 
 func (s *TimeplusClient) ListView() ([]View, error) {
 	url := fmt.Sprintf("%s/views", s.baseUrl())
-	_, respBody, err := utils.HttpRequestWithAPIKey(http.MethodGet, url, nil, s.client, s.apikey)
+	_, respBody, err := s.request(http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list views : %w", err)
 	}
@@ -247,130 +313,94 @@ func (s *TimeplusClient) ExistView(name string) bool {
 
 func (s *TimeplusClient) InsertData(data *IngestPayload) error {
 	url := fmt.Sprintf("%s/streams/%s/ingest", s.baseUrl(), data.Stream)
-	_, _, err := utils.HttpRequestWithAPIKey(http.MethodPost, url, data.Data, s.client, s.apikey)
+	_, _, err := s.request(http.MethodPost, url, data.Data)
 	if err != nil {
 		return fmt.Errorf("failed to ingest data into stream %s: %w", data.Stream, err)
 	}
 	return nil
 }
 
-func readCompleteLine(reader *bufio.Reader) (string, error) {
-	var line []byte
-	var isPrefix bool
-	var err error
+// ExecSQL runs a non streaming sql such as DDL, the timeout is in milliseconds
+func (s *TimeplusClient) ExecSQL(sql string, timeout int) (*QueryResult, error) {
+	url := fmt.Sprintf("%s/sql", s.baseUrl())
+	request := SQLRequest{
+		SQL:     sql,
+		Timeout: timeout,
+	}
+	_, respBody, err := s.request(http.MethodPost, url, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute sql : %w", err)
+	}
 
-	isPrefix = true
-	for isPrefix {
-		var chunk []byte
-		chunk, isPrefix, err = reader.ReadLine()
-		if err != nil {
-			return "", err
+	var result QueryResult
+	if len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, &result); err != nil {
+			return nil, fmt.Errorf("failed to unmarshall sql result: %w", err)
 		}
-		line = append(line, chunk...)
 	}
-
-	return string(line), nil
+	return &result, nil
 }
 
 func (s *TimeplusClient) queryStreamV2(sql string, batchCount int, batchBufferTime int) (*QueryResultStream, error) {
+	return s.QueryStreamWithOptions(sql, QueryOptions{
+		Policy: BatchingPolicy{
+			Count:  batchCount,
+			TimeMS: batchBufferTime,
+		},
+	})
+}
+
+// QueryStreamWithOptions runs a streaming query, the query result is delivered by ResultStream
+// while the other server events are passed to opts.OnEvent. ResultStream completes when the server
+// ends the query, a failed read such as a dropped connection is delivered once as error and ends it.
+func (s *TimeplusClient) QueryStreamWithOptions(sql string, opts QueryOptions) (*QueryResultStream, error) {
 	query := Query{
 		SQL:         sql,
 		Name:        "",
 		Description: "",
 		Tags:        []string{},
-		Policy: BatchingPolicy{
-			Count:  batchCount,
-			TimeMS: batchBufferTime,
-		},
+		Policy:      opts.Policy,
 	}
 
-	createQueryUrl := fmt.Sprintf("%s/queries", s.baseUrl())
-	config := utils.NewDefaultHTTPClientConfig()
-	res, err := utils.SSEHttpRequestWithAPIKey(http.MethodPost, createQueryUrl, query, config, s.apikey)
+	conn, err := s.openQuery(s.context(), query, opts.OnEvent)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create query : %w", err)
+		return nil, err
 	}
 
-	reader := bufio.NewReader(res.Body)
 	ch := make(chan rxgo.Item)
-	var queryMetadata QueryInfo
-
-	// Read the first result from sse which should be an event
-	line, err := readCompleteLine(reader)
-	if err != nil {
-		res.Body.Close()
-		return nil, fmt.Errorf("failed to retrieve query metadata: %w", err)
-	}
-	colonIndex := strings.Index(line, ":")
-	eventField := strings.TrimSpace(line[0:colonIndex])
-	eventData := strings.TrimSpace(line[colonIndex+1:])
-
-	if eventField != "event" {
-		res.Body.Close()
-		return nil, fmt.Errorf("the first result from sse has to be a event: %w", err)
-	}
-
-	dataLine, err := readCompleteLine(reader)
-	if err != nil {
-		res.Body.Close()
-		return nil, fmt.Errorf("failed to retrieve query metadata: %w", err)
-	}
-	colonIndex = strings.Index(dataLine, ":")
-	eventContentData := dataLine[colonIndex+1:]
-	if eventData == "query" {
-		err := json.Unmarshal([]byte(eventContentData), &queryMetadata)
-		if err != nil {
-			res.Body.Close()
-			return nil, fmt.Errorf("failed to unmarshall query header: %w", err)
-		}
-	} else {
-		res.Body.Close()
-		return nil, fmt.Errorf("the first event from sse has to be a query: %w", err)
-	}
+	ctx, stop := context.WithCancel(s.context())
 
 	// Read the rest in a streaming way
 	go func() {
-		defer res.Body.Close()
+		defer close(ch)
+		defer conn.close()
 
 		for {
-			line, err := readCompleteLine(reader)
-
+			event, err := conn.next()
 			if err != nil {
-				ch <- rxgo.Error(err)
-				continue
+				if err != io.EOF && ctx.Err() == nil {
+					sendItem(ctx, ch, rxgo.Error(err))
+				}
+				return
 			}
 
-			if len(line) == 0 {
-				continue
-			}
-
-			colonIndex := strings.Index(line, ":")
-			eventField := strings.TrimSpace(line[0:colonIndex])
-			eventData := strings.TrimSpace(line[colonIndex+1:])
-			if eventField == "event" {
-				_, err := readCompleteLine(reader)
-				if err != nil {
-					ch <- rxgo.Error(err)
-					continue
-				}
-			} else {
-				var m DataEvent
-				err := json.Unmarshal([]byte(eventData), &m)
-				if err != nil {
-					ch <- rxgo.Error(fmt.Errorf("invalide sse response, %s", line))
-				}
-				ch <- rxgo.Of(&m)
+			if !sendItem(ctx, ch, rxgo.Of(event)) {
+				return
 			}
 		}
 	}()
 
 	observable := rxgo.FromChannel(ch, rxgo.WithPublishStrategy())
-	_, cancel := observable.Connect(context.Background())
+	_, disconnect := observable.Connect(context.Background())
 
 	result := &QueryResultStream{
-		Metadata:     &queryMetadata,
+		Metadata:     &conn.metadata,
 		ResultStream: observable,
-		Cancel:       cancel,
+		Cancel: func() {
+			stop()
+			conn.close()
+			disconnect()
+		},
 	}
 	return result, nil
 }
@@ -378,3 +408,12 @@ func (s *TimeplusClient) queryStreamV2(sql string, batchCount int, batchBufferTi
 func (s *TimeplusClient) QueryStream(sql string, batchCount int, batchBufferTime int) (*QueryResultStream, error) {
 	return s.queryStreamV2(sql, batchCount, batchBufferTime)
 }
+
+// QueryStreamWithArgs binds args into the placeholders of sql in the client before running it, see Bind
+func (s *TimeplusClient) QueryStreamWithArgs(sql string, batchCount int, batchBufferTime int, args ...any) (*QueryResultStream, error) {
+	boundSQL, err := Bind(sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind query parameters: %w", err)
+	}
+	return s.queryStreamV2(boundSQL, batchCount, batchBufferTime)
+}
@@ -1,46 +1,172 @@
 package timeplus_test
 
 import (
-	"fmt"
-	"os"
+	"path/filepath"
 	"testing"
-	"time"
 
 	"github.com/timeplus-io/go-client/timeplus"
+	"github.com/timeplus-io/go-client/timeplustest"
+	"github.com/timeplus-io/go-client/utils"
 )
 
 func TestClient(t *testing.T) {
-	timeplusAddress := os.Getenv("TIMEPLUS_ADDRESS")
-	timeplusApiKey := os.Getenv("TIMEPLUS_API_KEY")
-	timeplusTenant := os.Getenv("TIMEPLUS_TENANT")
+	server := timeplustest.NewServer()
+	defer server.Close()
 
-	timeplusClient := timeplus.NewCient(timeplusAddress, timeplusTenant, timeplusApiKey)
-	queryResult, err := timeplusClient.QueryStream("select * from car_live_data", 100, 128)
+	header := []timeplus.ColumnDef{{Name: "id", Type: "string"}, {Name: "speed", Type: "float64"}}
+	server.ScriptQuery("car_live_data", timeplustest.QueryScript{
+		Header: header,
+		Events: []timeplustest.ScriptEvent{
+			timeplustest.Rows([]any{"c1", 10.5}, []any{"c2", 30}),
+			timeplustest.Rows([]any{"c3", 20}),
+		},
+		KeepOpen: true,
+	})
 
+	timeplusClient := server.Client()
+	queryResult, err := timeplusClient.QueryStream("select * from car_live_data", 100, 128)
 	if err != nil {
-		fmt.Printf("Query Failed! %s\n", err)
-		os.Exit(0)
+		t.Fatalf("query failed: %s", err)
 	}
 
-	fmt.Printf("query result header is, %v\n", queryResult.Metadata.Result.Header)
+	if len(queryResult.Metadata.Result.Header) != 2 || queryResult.Metadata.Result.Header[1].Name != "speed" {
+		t.Errorf("unexpected query result header %v", queryResult.Metadata.Result.Header)
+	}
 
+	rows := make([][]any, 0)
 	bufferStream := queryResult.ResultStream
 	disposed := bufferStream.ForEach(func(v interface{}) {
 		event := v.(*timeplus.DataEvent)
-		fmt.Printf("got one event %v\n", event)
+		rows = append(rows, *event...)
+		if len(rows) == 3 {
+			queryResult.Cancel()
+		}
 	}, func(err error) {
-		fmt.Printf("failed to query %s", err)
+		t.Errorf("failed to query %s", err)
 	}, func() {
+	})
+
+	<-disposed
+
+	if len(rows) != 3 || rows[0][0] != "c1" || rows[2][1] != float64(20) {
+		t.Errorf("unexpected rows %v", rows)
+	}
+}
+
+func TestClientUnixSocket(t *testing.T) {
+	server, err := timeplustest.NewUnixServer(filepath.Join(t.TempDir(), "proton.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
 
+	server.AddStream(timeplus.StreamDef{Name: "cars", Columns: []timeplus.ColumnDef{{Name: "id", Type: "string"}}})
+	server.ScriptQuery("cars", timeplustest.QueryScript{
+		Header: []timeplus.ColumnDef{{Name: "id", Type: "string"}},
+		Events: []timeplustest.ScriptEvent{timeplustest.Rows([]any{"c1"})},
 	})
 
-	go func(cancel func()) {
-		time.Sleep(3 * time.Second)
-		cancel()
-		fmt.Printf("cancel will close the channel for event")
-	}(queryResult.Cancel)
+	client := server.Client()
+	if !client.ExistStream("cars") {
+		t.Fatal("stream not found over unix socket")
+	}
 
-	<-disposed
+	payload := &timeplus.IngestPayload{
+		Stream: "cars",
+		Data:   timeplus.IngestData{Columns: []string{"id"}, Data: [][]any{{"c2"}}},
+	}
+	if err := server.LowLevelClient().InsertData(payload); err != nil {
+		t.Fatalf("low level ingest over unix socket failed: %s", err)
+	}
+	if rows := server.Rows("cars"); len(rows) != 1 || rows[0]["id"] != "c2" {
+		t.Errorf("unexpected rows %v", rows)
+	}
 
-	time.Sleep(2 * time.Second)
+	result, err := client.QueryStream("select * from cars", 10, 100)
+	if err != nil {
+		t.Fatalf("query over unix socket failed: %s", err)
+	}
+	defer result.Cancel()
+	item := <-result.ResultStream.Observe()
+	if item.E != nil || (*item.V.(*timeplus.DataEvent))[0][0] != "c1" {
+		t.Errorf("unexpected query result %v", item)
+	}
+}
+
+func TestClientWithCheckedHttpConfig(t *testing.T) {
+	config := utils.NewDefaultHTTPClientConfig()
+	config.TLS.CAFile = filepath.Join(t.TempDir(), "missing.pem")
+
+	if _, err := timeplus.NewCientWithCheckedHttpConfig("https://timeplus.internal", "", "", config); err == nil {
+		t.Errorf("expected the missing CA file to be reported")
+	}
+	if _, err := timeplus.NewLowLevelCientWithCheckedHttpConfig("https://timeplus.internal", config); err == nil {
+		t.Errorf("expected the missing CA file to be reported")
+	}
+}
+
+func TestClientWithIngester(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	server.AddStream(timeplus.StreamDef{Name: "cars", Columns: []timeplus.ColumnDef{{Name: "id", Type: "string"}}})
+
+	client := timeplus.WithIngester(server.Client(), timeplus.NewLowLevelCient(server.URL))
+	if !client.ExistStream("cars") {
+		t.Fatalf("stream not listed by the wrapped client")
+	}
+	err := client.InsertData(&timeplus.IngestPayload{
+		Stream: "cars",
+		Data:   timeplus.IngestData{Columns: []string{"id"}, Data: [][]any{{"c1"}}},
+	})
+	if err != nil {
+		t.Fatalf("ingest failed: %s", err)
+	}
+
+	requests := server.Requests()
+	if last := requests[len(requests)-1]; last.Path != "/proton/v1/ingest/streams/cars" {
+		t.Errorf("expected the rows to be sent by the ingester, got %s", last.Path)
+	}
+	if rows := server.Rows("cars"); len(rows) != 1 {
+		t.Errorf("unexpected rows %v", rows)
+	}
+}
+
+func TestClientQueryEnd(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	header := []timeplus.ColumnDef{{Name: "id", Type: "string"}}
+	server.ScriptQuery("from ended", timeplustest.QueryScript{
+		Header: header,
+		Events: []timeplustest.ScriptEvent{timeplustest.Rows([]any{"c1"})},
+	})
+	server.ScriptQuery("from dropped", timeplustest.QueryScript{
+		Header: header,
+		Events: []timeplustest.ScriptEvent{timeplustest.Rows([]any{"c1"}), timeplustest.Disconnect()},
+	})
+
+	for _, test := range []struct {
+		sql    string
+		errors int
+	}{
+		{sql: "select * from ended"},
+		{sql: "select * from dropped", errors: 1},
+	} {
+		result, err := server.Client().QueryStream(test.sql, 10, 100)
+		if err != nil {
+			t.Fatalf("query failed: %s", err)
+		}
+
+		rows, errors := 0, 0
+		for item := range result.ResultStream.Observe() {
+			if item.E != nil {
+				errors++
+				continue
+			}
+			rows += len(*item.V.(*timeplus.DataEvent))
+		}
+		if rows != 1 || errors != test.errors {
+			t.Errorf("%s: expected 1 row and %d errors, got %d rows and %d errors", test.sql, test.errors, rows, errors)
+		}
+		result.Cancel()
+	}
 }
@@ -0,0 +1,222 @@
+package timeplus_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/timeplus-io/go-client/timeplus"
+	"github.com/timeplus-io/go-client/timeplustest"
+	"github.com/timeplus-io/go-client/utils"
+)
+
+func newEndpointServers(t *testing.T, count int) ([]*timeplustest.Server, []string) {
+	servers := make([]*timeplustest.Server, count)
+	addresses := make([]string, count)
+	for index := range servers {
+		servers[index] = timeplustest.NewServer()
+		t.Cleanup(servers[index].Close)
+		servers[index].AddStream(timeplus.StreamDef{Name: "cars", Columns: []timeplus.ColumnDef{{Name: "id", Type: "string"}}})
+		addresses[index] = servers[index].URL
+	}
+	return servers, addresses
+}
+
+func newEndpointPool(t *testing.T, config utils.EndpointPoolConfig) *utils.EndpointPool {
+	pool, err := utils.NewEndpointPool(config, *utils.NewDefaultHTTPClientConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func ingestCar(t *testing.T, client timeplus.Ingester, id string) {
+	payload := &timeplus.IngestPayload{
+		Stream: "cars",
+		Data:   timeplus.IngestData{Columns: []string{"id"}, Data: [][]any{{id}}},
+	}
+	if err := client.InsertData(payload); err != nil {
+		t.Fatalf("ingest failed: %s", err)
+	}
+}
+
+func TestMultiEndpointRoundRobin(t *testing.T) {
+	servers, addresses := newEndpointServers(t, 2)
+	pool := newEndpointPool(t, utils.EndpointPoolConfig{Endpoints: addresses, HealthCheckInterval: -1})
+
+	client := timeplus.NewMultiEndpointCient(pool, "", "", utils.NewDefaultHTTPClientConfig())
+	lowLevelClient := timeplus.NewMultiEndpointLowLevelCient(pool, utils.NewDefaultHTTPClientConfig())
+	for i := 0; i < 2; i++ {
+		ingestCar(t, client, "c1")
+		ingestCar(t, lowLevelClient, "c2")
+	}
+
+	for index, server := range servers {
+		if rows := server.Rows("cars"); len(rows) != 2 {
+			t.Errorf("expected 2 rows on endpoint %d, got %v", index, rows)
+		}
+	}
+}
+
+func TestMultiEndpointFailover(t *testing.T) {
+	servers, addresses := newEndpointServers(t, 2)
+	pool := newEndpointPool(t, utils.EndpointPoolConfig{Endpoints: addresses, HealthCheckInterval: -1})
+	client := timeplus.NewMultiEndpointCient(pool, "", "", utils.NewDefaultHTTPClientConfig())
+
+	servers[0].Close()
+	for i := 0; i < 3; i++ {
+		ingestCar(t, client, "c1")
+	}
+
+	if rows := servers[1].Rows("cars"); len(rows) != 3 {
+		t.Errorf("expected all rows on the remaining endpoint, got %v", rows)
+	}
+	if status := pool.Endpoints(); status[0].Healthy || !status[1].Healthy {
+		t.Errorf("unexpected endpoint health %v", status)
+	}
+}
+
+func TestMultiEndpointHealthCheck(t *testing.T) {
+	servers, addresses := newEndpointServers(t, 2)
+	servers[0].InjectFault(timeplustest.Fault{Path: "/health", Status: 503})
+	pool := newEndpointPool(t, utils.EndpointPoolConfig{
+		Endpoints:           addresses,
+		HealthCheckPath:     "/health",
+		HealthCheckInterval: -1,
+	})
+	pool.CheckHealth(context.Background())
+
+	client := timeplus.NewMultiEndpointCient(pool, "", "", utils.NewDefaultHTTPClientConfig())
+	for i := 0; i < 2; i++ {
+		ingestCar(t, client, "c1")
+	}
+	if rows := servers[0].Rows("cars"); len(rows) != 0 {
+		t.Errorf("unhealthy endpoint should not be used, got %v", rows)
+	}
+
+	servers[0].ClearFaults()
+	pool.CheckHealth(context.Background())
+	if status := pool.Endpoints(); !status[0].Healthy {
+		t.Errorf("recovered endpoint still unhealthy")
+	}
+}
+
+func queriesOf(server *timeplustest.Server) int {
+	count := 0
+	for _, req := range server.Requests() {
+		if req.Path == "/api/"+timeplus.APIVersion+"/queries" {
+			count++
+		}
+	}
+	return count
+}
+
+func TestMultiEndpointQueries(t *testing.T) {
+	servers, addresses := newEndpointServers(t, 3)
+	for _, server := range servers {
+		server.ScriptQuery("cars", timeplustest.QueryScript{
+			Header:   []timeplus.ColumnDef{{Name: "id", Type: "string"}},
+			Events:   []timeplustest.ScriptEvent{timeplustest.Rows([]any{"c1"})},
+			KeepOpen: true,
+		})
+	}
+	pool := newEndpointPool(t, utils.EndpointPoolConfig{Endpoints: addresses, Policy: utils.LeastLoaded, HealthCheckInterval: -1})
+	client := timeplus.NewMultiEndpointCient(pool, "", "", utils.NewDefaultHTTPClientConfig())
+
+	// the running queries are in flight, so each new query goes to the least loaded endpoint
+	for i := 0; i < 3; i++ {
+		result, err := client.QueryStream("select * from cars", 10, 100)
+		if err != nil {
+			t.Fatalf("query failed: %s", err)
+		}
+		defer result.Cancel()
+		<-result.ResultStream.Observe()
+	}
+
+	for index, server := range servers {
+		if count := queriesOf(server); count != 1 {
+			t.Errorf("expected one query on endpoint %d, got %d", index, count)
+		}
+	}
+}
+
+func TestMultiEndpointResumableQuery(t *testing.T) {
+	servers, addresses := newEndpointServers(t, 3)
+	for _, server := range servers {
+		server.ScriptQuery("cars", timeplustest.QueryScript{
+			Header: []timeplus.ColumnDef{{Name: "_tp_time", Type: "datetime64(3)"}, {Name: "id", Type: "string"}},
+			Events: []timeplustest.ScriptEvent{
+				timeplustest.Rows([]any{"2023-01-01 00:00:01.000", "c1"}),
+				timeplustest.Disconnect(),
+			},
+			Times: 1,
+		})
+		server.ScriptQuery("cars", timeplustest.QueryScript{
+			Header:   []timeplus.ColumnDef{{Name: "_tp_time", Type: "datetime64(3)"}, {Name: "id", Type: "string"}},
+			Events:   []timeplustest.ScriptEvent{timeplustest.Rows([]any{"2023-01-01 00:00:02.000", "c2"})},
+			KeepOpen: true,
+		})
+	}
+	pool := newEndpointPool(t, utils.EndpointPoolConfig{Endpoints: addresses, HealthCheckInterval: -1})
+	client := timeplus.NewMultiEndpointCient(pool, "", "", utils.NewDefaultHTTPClientConfig())
+
+	result, err := client.QueryStreamResumable("select * from cars", 10, 100, timeplus.ResumeOptions{InitialBackoff: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("query failed: %s", err)
+	}
+	defer result.Cancel()
+
+	items := result.ResultStream.Observe()
+	for i := 0; i < 2; i++ {
+		select {
+		case item := <-items:
+			if item.E != nil {
+				t.Fatalf("unexpected error %s", item.E)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("query not resumed")
+		}
+	}
+
+	// the reconnect goes to the endpoint of the query although round robin moved on
+	for index, server := range servers {
+		if count := queriesOf(server); count != 0 && count != 2 {
+			t.Errorf("reconnect not sticky, endpoint %d got %d queries", index, count)
+		}
+	}
+}
+
+func TestMultiEndpointNoFailoverAfterConnect(t *testing.T) {
+	dropping := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the request has reached the server, which fails before answering
+		panic(http.ErrAbortHandler)
+	}))
+	defer dropping.Close()
+	servers, addresses := newEndpointServers(t, 1)
+
+	pool := newEndpointPool(t, utils.EndpointPoolConfig{Endpoints: []string{dropping.URL, addresses[0]}, HealthCheckInterval: -1})
+	client := timeplus.NewMultiEndpointLowLevelCient(pool, utils.NewDefaultHTTPClientConfig())
+
+	payload := &timeplus.IngestPayload{
+		Stream: "cars",
+		Data:   timeplus.IngestData{Columns: []string{"id"}, Data: [][]any{{"c1"}}},
+	}
+	if err := client.InsertData(payload); err == nil {
+		t.Errorf("expected the dropped request to fail")
+	}
+	if rows := servers[0].Rows("cars"); len(rows) != 0 {
+		t.Errorf("request sent again to another endpoint, got %v", rows)
+	}
+}
+
+func TestMultiEndpointInvalidConfig(t *testing.T) {
+	if _, err := utils.NewEndpointPool(utils.EndpointPoolConfig{}, *utils.NewDefaultHTTPClientConfig()); err == nil {
+		t.Errorf("expected pool without endpoints to be rejected")
+	}
+	if _, err := utils.NewEndpointPool(utils.EndpointPoolConfig{Endpoints: []string{"proton:8000"}}, *utils.NewDefaultHTTPClientConfig()); err == nil {
+		t.Errorf("expected endpoint without scheme to be rejected")
+	}
+}
@@ -0,0 +1,101 @@
+package timeplus
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// The server events of a running query besides the query result
+const (
+	// QueryEventQuery carries the QueryInfo of the query, it is sent again when the status changes
+	QueryEventQuery = "query"
+	// QueryEventMetrics carries the QueryMetrics reporting the progress of the query
+	QueryEventMetrics = "metrics"
+	// QueryEventError carries the QueryError which ends the query
+	QueryEventError = "error"
+)
+
+type QueryOptions struct {
+	// Policy is sent to the server, which batches the query result with it, the server default is used for zero values
+	Policy BatchingPolicy
+	// OnEvent receives the server events other than the query result, it is called from the
+	// goroutine reading the query so it should not block
+	OnEvent func(QueryEvent)
+}
+
+// QueryEvent is a non data event sent by the server while the query is running
+type QueryEvent struct {
+	Type string
+	Data json.RawMessage
+}
+
+type QueryMetrics struct {
+	Count             int64   `json:"count"`
+	EPS               float64 `json:"eps"`
+	ProcessingTime    int64   `json:"processing_time"`
+	LastEventSentTime int64   `json:"last_event_sent_time"`
+}
+
+type QueryError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *QueryError) Error() string {
+	if e.Code == 0 {
+		return fmt.Sprintf("query failed: %s", e.Message)
+	}
+	return fmt.Sprintf("query failed with code %d: %s", e.Code, e.Message)
+}
+
+// Validate rejects negative values before the policy is sent to the server
+func (p BatchingPolicy) Validate() error {
+	if p.Count < 0 {
+		return fmt.Errorf("invalid batching policy, count %d is negative", p.Count)
+	}
+	if p.TimeMS < 0 {
+		return fmt.Errorf("invalid batching policy, time_ms %d is negative", p.TimeMS)
+	}
+	return nil
+}
+
+// Query decodes the QueryInfo of a QueryEventQuery event
+func (e QueryEvent) Query() (*QueryInfo, error) {
+	var info QueryInfo
+	if err := e.decode(QueryEventQuery, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Metrics decodes the QueryMetrics of a QueryEventMetrics event
+func (e QueryEvent) Metrics() (*QueryMetrics, error) {
+	var metrics QueryMetrics
+	if err := e.decode(QueryEventMetrics, &metrics); err != nil {
+		return nil, err
+	}
+	return &metrics, nil
+}
+
+// Error decodes the QueryError of a QueryEventError event, the error may be sent as object or plain message
+func (e QueryEvent) Error() error {
+	queryErr := &QueryError{}
+	if err := json.Unmarshal(e.Data, queryErr); err != nil {
+		var message string
+		if err := json.Unmarshal(e.Data, &message); err != nil {
+			message = string(e.Data)
+		}
+		queryErr.Message = message
+	}
+	return queryErr
+}
+
+func (e QueryEvent) decode(expected string, v any) error {
+	if e.Type != expected {
+		return fmt.Errorf("event %s is not a %s event", e.Type, expected)
+	}
+	if err := json.Unmarshal(e.Data, v); err != nil {
+		return fmt.Errorf("failed to unmarshall %s event: %w", e.Type, err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package timeplus_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/timeplus-io/go-client/timeplus"
+)
+
+func TestQueryEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: query\ndata: %s\n\n", testQueryHeader)
+		fmt.Fprint(w, "data: [[\"2023-01-01 00:00:01.000\", 1]]\n\n")
+		fmt.Fprint(w, "event: metrics\ndata: {\"count\":1,\"eps\":2.5,\"processing_time\":10}\n\n")
+		fmt.Fprint(w, "event: error\ndata: {\"code\":62,\"message\":\"syntax error\"}\n\n")
+	}))
+	defer server.Close()
+
+	events := make([]timeplus.QueryEvent, 0)
+	client := timeplus.NewCient(server.URL, "", "key")
+	result, err := client.QueryStreamWithOptions("select * from s", timeplus.QueryOptions{
+		Policy: timeplus.BatchingPolicy{Count: 10},
+		OnEvent: func(e timeplus.QueryEvent) {
+			events = append(events, e)
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to query: %s", err)
+	}
+
+	var batches int
+	var queryErr *timeplus.QueryError
+	for item := range result.ResultStream.Observe() {
+		if item.E != nil {
+			if !errors.As(item.E, &queryErr) {
+				t.Errorf("unexpected error %s", item.E)
+			}
+			break
+		}
+		batches++
+	}
+
+	if batches != 1 {
+		t.Errorf("expected 1 batch, got %d", batches)
+	}
+	if queryErr == nil || queryErr.Code != 62 || queryErr.Message != "syntax error" {
+		t.Errorf("unexpected query error %v", queryErr)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	metrics, err := events[0].Metrics()
+	if err != nil || metrics.Count != 1 || metrics.EPS != 2.5 || metrics.ProcessingTime != 10 {
+		t.Errorf("unexpected metrics %+v, %v", metrics, err)
+	}
+	if _, err := events[1].Metrics(); err == nil {
+		t.Errorf("expected error decoding an error event as metrics")
+	}
+
+	if _, err := client.QueryStreamWithOptions("select 1", timeplus.QueryOptions{Policy: timeplus.BatchingPolicy{TimeMS: -1}}); err == nil {
+		t.Errorf("expected error for negative batching policy")
+	}
+}
@@ -0,0 +1,323 @@
+package timeplus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+const DefaultSubscriptionBufferSize = 16
+
+var ErrSlowConsumer = errors.New("subscription disconnected as a slow consumer")
+var ErrHubClosed = errors.New("query hub closed")
+
+// SlowConsumerPolicy decides what happens when the buffer of a subscriber is full
+type SlowConsumerPolicy int
+
+const (
+	// BlockSlowConsumer waits for the subscriber, which holds back all other subscribers of the query
+	BlockSlowConsumer SlowConsumerPolicy = iota
+	// DropNewest drops the batch which does not fit into the buffer
+	DropNewest
+	// DropOldest drops the oldest buffered batch to make room for the new one
+	DropOldest
+	// DisconnectSlowConsumer ends the subscription with ErrSlowConsumer
+	DisconnectSlowConsumer
+)
+
+type SubscribeOptions struct {
+	BufferSize         int
+	SlowConsumerPolicy SlowConsumerPolicy
+}
+
+type hubKey struct {
+	sql    string
+	policy BatchingPolicy
+}
+
+// QueryHub shares one server side query among all subscribers of the same sql and batching policy.
+// The query is created with the first subscriber and cancelled when the last one leaves.
+// The queries run in a context derived from the context of the client, it is cancelled by Close.
+type QueryHub struct {
+	client  *TimeplusClient
+	ctx     context.Context
+	cancel  context.CancelFunc
+	lock    sync.Mutex
+	queries map[hubKey]*sharedQuery
+	closed  bool
+}
+
+type sharedQuery struct {
+	hub  *QueryHub
+	key  hubKey
+	conn *queryConn
+	// ready is closed once the query is opened, or failed to open with openErr
+	ready   chan struct{}
+	openErr error
+	subs    map[*Subscription]struct{}
+	closed  bool
+}
+
+type Subscription struct {
+	// C receives the query result, it is closed when the subscription ends
+	C        <-chan *DataEvent
+	Metadata *QueryInfo
+
+	query      *sharedQuery
+	ch         chan *DataEvent
+	policy     SlowConsumerPolicy
+	done       chan struct{}
+	lock       sync.Mutex
+	closed     bool
+	finishOnce sync.Once
+	err        error
+	dropped    uint64
+}
+
+func NewQueryHub(client *TimeplusClient) *QueryHub {
+	ctx, cancel := context.WithCancel(client.context())
+	return &QueryHub{
+		client:  client,
+		ctx:     ctx,
+		cancel:  cancel,
+		queries: make(map[hubKey]*sharedQuery),
+	}
+}
+
+// Subscribe joins the running query of sql and policy or creates it if there is none
+func (h *QueryHub) Subscribe(sql string, policy BatchingPolicy, opts SubscribeOptions) (*Subscription, error) {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultSubscriptionBufferSize
+	}
+
+	key := hubKey{sql: sql, policy: policy}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for {
+		if h.closed {
+			return nil, ErrHubClosed
+		}
+
+		q, ok := h.queries[key]
+		if !ok {
+			// the query is opened without holding the hub, concurrent subscribers wait for it to be ready
+			q = &sharedQuery{
+				hub:   h,
+				key:   key,
+				ready: make(chan struct{}),
+				subs:  make(map[*Subscription]struct{}),
+			}
+			h.queries[key] = q
+			h.lock.Unlock()
+			conn, err := h.client.openQuery(h.ctx, Query{
+				SQL:    sql,
+				Tags:   []string{},
+				Policy: policy,
+			}, nil)
+			h.lock.Lock()
+
+			q.conn = conn
+			q.openErr = err
+			if h.closed {
+				q.openErr = ErrHubClosed
+				if err == nil {
+					conn.close()
+				}
+			}
+			if q.openErr != nil {
+				q.closed = true
+				if h.queries[key] == q {
+					delete(h.queries, key)
+				}
+				close(q.ready)
+				return nil, q.openErr
+			}
+			close(q.ready)
+			go q.run()
+		} else {
+			h.lock.Unlock()
+			<-q.ready
+			h.lock.Lock()
+			if q.openErr != nil {
+				return nil, q.openErr
+			}
+			if q.closed {
+				// the query ended while waiting, open it again
+				continue
+			}
+		}
+
+		ch := make(chan *DataEvent, opts.BufferSize)
+		sub := &Subscription{
+			C:        ch,
+			Metadata: &q.conn.metadata,
+			query:    q,
+			ch:       ch,
+			policy:   opts.SlowConsumerPolicy,
+			done:     make(chan struct{}),
+		}
+		q.subs[sub] = struct{}{}
+		return sub, nil
+	}
+}
+
+// Close ends all queries of the hub and their subscriptions, later subscribes fail with ErrHubClosed
+func (h *QueryHub) Close() {
+	h.lock.Lock()
+	h.closed = true
+	conns := make([]*queryConn, 0, len(h.queries))
+	for _, q := range h.queries {
+		// the queries being opened are closed by their subscriber
+		if q.conn != nil && !q.closed {
+			q.closed = true
+			conns = append(conns, q.conn)
+		}
+	}
+	h.lock.Unlock()
+
+	// the queries being opened give up instead of waiting for the server
+	h.cancel()
+	for _, conn := range conns {
+		conn.close()
+	}
+}
+
+func (q *sharedQuery) run() {
+	var err error
+	for {
+		var event *DataEvent
+		event, err = q.conn.next()
+		if err != nil {
+			break
+		}
+
+		q.hub.lock.Lock()
+		subs := make([]*Subscription, 0, len(q.subs))
+		for sub := range q.subs {
+			subs = append(subs, sub)
+		}
+		q.hub.lock.Unlock()
+
+		for _, sub := range subs {
+			if !sub.deliver(event) {
+				q.remove(sub, ErrSlowConsumer)
+			}
+		}
+	}
+
+	q.hub.lock.Lock()
+	wasClosed := q.closed
+	q.closed = true
+	if q.hub.queries[q.key] == q {
+		delete(q.hub.queries, q.key)
+	}
+	subs := q.subs
+	q.subs = make(map[*Subscription]struct{})
+	q.hub.lock.Unlock()
+
+	q.conn.close()
+	if wasClosed || err == io.EOF {
+		err = nil
+	} else {
+		err = fmt.Errorf("query failed: %w", err)
+	}
+	for sub := range subs {
+		sub.finish(err)
+	}
+}
+
+// remove detaches sub and cancels the query if it was the last subscriber
+func (q *sharedQuery) remove(sub *Subscription, err error) {
+	q.hub.lock.Lock()
+	delete(q.subs, sub)
+	if len(q.subs) == 0 && !q.closed {
+		q.closed = true
+		if q.hub.queries[q.key] == q {
+			delete(q.hub.queries, q.key)
+		}
+		q.conn.close()
+	}
+	q.hub.lock.Unlock()
+
+	sub.finish(err)
+}
+
+// deliver returns false if the subscriber has to be disconnected
+func (s *Subscription) deliver(event *DataEvent) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		return true
+	}
+
+	switch s.policy {
+	case DropNewest:
+		select {
+		case s.ch <- event:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case s.ch <- event:
+				return true
+			default:
+			}
+			select {
+			case <-s.ch:
+				atomic.AddUint64(&s.dropped, 1)
+			default:
+			}
+		}
+	case DisconnectSlowConsumer:
+		select {
+		case s.ch <- event:
+		default:
+			return false
+		}
+	default:
+		select {
+		case s.ch <- event:
+		case <-s.done:
+		}
+	}
+	return true
+}
+
+func (s *Subscription) finish(err error) {
+	s.finishOnce.Do(func() {
+		s.err = err
+		close(s.done)
+
+		s.lock.Lock()
+		s.closed = true
+		close(s.ch)
+		s.lock.Unlock()
+	})
+}
+
+// Unsubscribe leaves the query, the query is cancelled when the last subscriber leaves
+func (s *Subscription) Unsubscribe() {
+	s.query.remove(s, nil)
+}
+
+// Err returns why the subscription ended, it is nil while running or if the subscriber left
+func (s *Subscription) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Dropped returns the number of batches dropped because the subscriber was too slow
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
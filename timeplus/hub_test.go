@@ -0,0 +1,217 @@
+package timeplus_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/timeplus-io/go-client/timeplus"
+)
+
+func TestQueryHub(t *testing.T) {
+	var opened, closed int32
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&opened, 1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: query\ndata: %s\n\n", testQueryHeader)
+		w.(http.Flusher).Flush()
+
+		<-release
+		fmt.Fprint(w, "data: [[\"2023-01-01 00:00:01.000\", 1]]\n\n")
+		fmt.Fprint(w, "data: [[\"2023-01-01 00:00:02.000\", 2]]\n\n")
+		w.(http.Flusher).Flush()
+
+		<-r.Context().Done()
+		atomic.AddInt32(&closed, 1)
+	}))
+	defer server.Close()
+
+	hub := timeplus.NewQueryHub(timeplus.NewCient(server.URL, "", "key"))
+	policy := timeplus.BatchingPolicy{Count: 10, TimeMS: 100}
+
+	first, err := hub.Subscribe("select * from s", policy, timeplus.SubscribeOptions{})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %s", err)
+	}
+	second, err := hub.Subscribe("select * from s", policy, timeplus.SubscribeOptions{BufferSize: 1, SlowConsumerPolicy: timeplus.DropOldest})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %s", err)
+	}
+	if first.Metadata.ID != "q1" || second.Metadata != first.Metadata {
+		t.Errorf("subscribers should share the query metadata")
+	}
+	close(release)
+
+	for i := 1; i <= 2; i++ {
+		event := <-first.C
+		if (*event)[0][1].(float64) != float64(i) {
+			t.Errorf("unexpected event %v", *event)
+		}
+	}
+
+	// the second subscriber did not read, so only the latest batch is kept
+	time.Sleep(50 * time.Millisecond)
+	event := <-second.C
+	if (*event)[0][1].(float64) != 2 || second.Dropped() != 1 {
+		t.Errorf("unexpected event %v with %d dropped", *event, second.Dropped())
+	}
+
+	first.Unsubscribe()
+	if _, ok := <-first.C; ok {
+		t.Errorf("channel should be closed after unsubscribe")
+	}
+	if atomic.LoadInt32(&closed) != 0 {
+		t.Errorf("query should keep running while there are subscribers")
+	}
+
+	second.Unsubscribe()
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&closed) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if atomic.LoadInt32(&closed) != 1 || atomic.LoadInt32(&opened) != 1 {
+		t.Errorf("expected one query opened and closed, got %d opened %d closed", opened, closed)
+	}
+}
+
+func TestQueryHubSlowOpen(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var query timeplus.Query
+		json.NewDecoder(r.Body).Decode(&query)
+		if strings.Contains(query.SQL, "slow") {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: query\ndata: %s\n\n", testQueryHeader)
+		fmt.Fprint(w, "data: [[\"2023-01-01 00:00:01.000\", 1]]\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	defer close(release)
+
+	hub := timeplus.NewQueryHub(timeplus.NewCient(server.URL, "", "key"))
+	defer hub.Close()
+	policy := timeplus.BatchingPolicy{Count: 10, TimeMS: 100}
+
+	go hub.Subscribe("select * from slow", policy, timeplus.SubscribeOptions{})
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() {
+		sub, err := hub.Subscribe("select * from fast", policy, timeplus.SubscribeOptions{})
+		if err == nil {
+			<-sub.C
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("failed to subscribe: %s", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("opening a query blocked the other queries of the hub")
+	}
+}
+
+func TestQueryHubClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: query\ndata: %s\n\n", testQueryHeader)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	hub := timeplus.NewQueryHub(timeplus.NewCient(server.URL, "", "key"))
+	policy := timeplus.BatchingPolicy{Count: 10, TimeMS: 100}
+	sub, err := hub.Subscribe("select * from s", policy, timeplus.SubscribeOptions{})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %s", err)
+	}
+
+	hub.Close()
+	if _, ok := <-sub.C; ok {
+		t.Errorf("channel should be closed when the hub is closed")
+	}
+	if sub.Err() != nil {
+		t.Errorf("closing the hub is not a query failure, got %s", sub.Err())
+	}
+	if _, err := hub.Subscribe("select * from s", policy, timeplus.SubscribeOptions{}); !errors.Is(err, timeplus.ErrHubClosed) {
+		t.Errorf("expected ErrHubClosed, got %v", err)
+	}
+}
+
+func TestQueryHubCloseWhileOpening(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the disconnect of the client is noticed once the body is read
+		io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	hub := timeplus.NewQueryHub(timeplus.NewCient(server.URL, "", "key"))
+	done := make(chan error, 1)
+	go func() {
+		_, err := hub.Subscribe("select * from s", timeplus.BatchingPolicy{}, timeplus.SubscribeOptions{})
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	hub.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, timeplus.ErrHubClosed) {
+			t.Errorf("expected ErrHubClosed, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("closing the hub did not abort the query being opened")
+	}
+}
+
+func TestQueryHubClientContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: query\ndata: %s\n\n", testQueryHeader)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	hub := timeplus.NewQueryHub(timeplus.NewCient(server.URL, "", "key").WithContext(ctx))
+	defer hub.Close()
+	sub, err := hub.Subscribe("select * from s", timeplus.BatchingPolicy{}, timeplus.SubscribeOptions{})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %s", err)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-sub.C:
+		if ok {
+			t.Errorf("expected no data")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("cancelling the client context did not end the subscription")
+	}
+	if !errors.Is(sub.Err(), context.Canceled) {
+		t.Errorf("expected the cancelled context as error, got %v", sub.Err())
+	}
+}
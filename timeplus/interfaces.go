@@ -0,0 +1,65 @@
+package timeplus
+
+// StreamManager manages the streams and views
+type StreamManager interface {
+	CreateStream(streamDef StreamDef) error
+	DeleteStream(streamName string) error
+	ExistStream(name string) bool
+	GetStream(name string) (*StreamDef, error)
+	ListStream() ([]StreamDef, error)
+	CreateView(view View) error
+	ListView() ([]View, error)
+	ExistView(name string) bool
+}
+
+// Ingester writes rows into a stream, it is implemented by TimeplusClient and TimeplusLowLevelClient
+type Ingester interface {
+	InsertData(data *IngestPayload) error
+}
+
+// SQLExecutor runs plain sql such as DDL
+type SQLExecutor interface {
+	ExecSQL(sql string, timeout int) (*QueryResult, error)
+}
+
+// Querier runs streaming queries and plain sql
+type Querier interface {
+	SQLExecutor
+	QueryStream(sql string, batchCount int, batchBufferTime int) (*QueryResultStream, error)
+	QueryStreamWithOptions(sql string, opts QueryOptions) (*QueryResultStream, error)
+	QueryStreamWithArgs(sql string, batchCount int, batchBufferTime int, args ...any) (*QueryResultStream, error)
+}
+
+// StreamWriter creates streams and writes into them, the log handler and the exporters accept it
+type StreamWriter interface {
+	StreamManager
+	Ingester
+}
+
+// Client is implemented by TimeplusClient, the metrics and the other helpers accept it
+// so they can be used with a mock or a decorated client
+type Client interface {
+	StreamManager
+	Ingester
+	Querier
+}
+
+var (
+	_ Client   = (*TimeplusClient)(nil)
+	_ Ingester = (*TimeplusLowLevelClient)(nil)
+)
+
+// WithIngester returns client writing its rows with ingester, such as a TimeplusLowLevelClient,
+// while the streams are still managed by client
+func WithIngester(client Client, ingester Ingester) Client {
+	return &ingestClient{Client: client, ingester: ingester}
+}
+
+type ingestClient struct {
+	Client
+	ingester Ingester
+}
+
+func (c *ingestClient) InsertData(data *IngestPayload) error {
+	return c.ingester.InsertData(data)
+}
@@ -1,6 +1,7 @@
 package timeplus
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -10,13 +11,50 @@ import (
 type TimeplusLowLevelClient struct {
 	address string
 	client  *http.Client
+	// ctx is set by WithContext, nil is the background context
+	ctx context.Context
 }
 
 func NewLowLevelCient(address string) *TimeplusLowLevelClient {
+	return NewLowLevelCientWithHttpConfig(address, utils.NewDefaultHTTPClientConfig())
+}
+
+// NewLowLevelCientWithHttpConfig creates a client of address, which is either a http(s) url or a unix:// socket path
+func NewLowLevelCientWithHttpConfig(address string, config *utils.HTTPClientConfig) *TimeplusLowLevelClient {
+	address, resolved := utils.ResolveAddress(address, *config)
 	return &TimeplusLowLevelClient{
 		address: address,
-		client:  utils.NewDefaultHttpClient(),
+		client:  utils.NewHttpClient(resolved),
+	}
+}
+
+// NewLowLevelCientWithCheckedHttpConfig is NewLowLevelCientWithHttpConfig failing on a config which cannot be loaded
+func NewLowLevelCientWithCheckedHttpConfig(address string, config *utils.HTTPClientConfig) (*TimeplusLowLevelClient, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return NewLowLevelCientWithHttpConfig(address, config), nil
+}
+
+// NewMultiEndpointLowLevelCient creates a client spreading its requests over the endpoints of pool, see utils.EndpointPool
+func NewMultiEndpointLowLevelCient(pool *utils.EndpointPool, config *utils.HTTPClientConfig) *TimeplusLowLevelClient {
+	withPool := *config
+	withPool.Endpoints = pool
+	return NewLowLevelCientWithHttpConfig(utils.EndpointPoolAddress, &withPool)
+}
+
+// WithContext returns a copy of the client sending its requests with ctx, see TimeplusClient.WithContext
+func (s *TimeplusLowLevelClient) WithContext(ctx context.Context) *TimeplusLowLevelClient {
+	copied := *s
+	copied.ctx = ctx
+	return &copied
+}
+
+func (s *TimeplusLowLevelClient) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
 	}
+	return s.ctx
 }
 
 func (s *TimeplusLowLevelClient) baseUrl() string {
@@ -25,7 +63,7 @@ func (s *TimeplusLowLevelClient) baseUrl() string {
 
 func (s *TimeplusLowLevelClient) InsertData(data *IngestPayload) error {
 	url := fmt.Sprintf("%s/%s/%s", s.baseUrl(), "ingest/streams", data.Stream)
-	_, _, err := utils.HttpRequest(http.MethodPost, url, data.Data, s.client)
+	_, _, err := utils.HttpRequestWithContext(s.context(), http.MethodPost, url, data.Data, s.client, map[string]string{})
 	if err != nil {
 		return fmt.Errorf("failed to ingest data into stream %s: %w", data.Stream, err)
 	}
@@ -0,0 +1,732 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package mocks
+
+import (
+	mock "github.com/stretchr/testify/mock"
+	timeplus "github.com/timeplus-io/go-client/timeplus"
+)
+
+// Client is an autogenerated mock type for the Client type
+type Client struct {
+	mock.Mock
+}
+
+type Client_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Client) EXPECT() *Client_Expecter {
+	return &Client_Expecter{mock: &_m.Mock}
+}
+
+// CreateStream provides a mock function with given fields: streamDef
+func (_m *Client) CreateStream(streamDef timeplus.StreamDef) error {
+	ret := _m.Called(streamDef)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateStream")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(timeplus.StreamDef) error); ok {
+		r0 = rf(streamDef)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Client_CreateStream_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateStream'
+type Client_CreateStream_Call struct {
+	*mock.Call
+}
+
+// CreateStream is a helper method to define mock.On call
+//   - streamDef timeplus.StreamDef
+func (_e *Client_Expecter) CreateStream(streamDef interface{}) *Client_CreateStream_Call {
+	return &Client_CreateStream_Call{Call: _e.mock.On("CreateStream", streamDef)}
+}
+
+func (_c *Client_CreateStream_Call) Run(run func(streamDef timeplus.StreamDef)) *Client_CreateStream_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(timeplus.StreamDef))
+	})
+	return _c
+}
+
+func (_c *Client_CreateStream_Call) Return(_a0 error) *Client_CreateStream_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Client_CreateStream_Call) RunAndReturn(run func(timeplus.StreamDef) error) *Client_CreateStream_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CreateView provides a mock function with given fields: view
+func (_m *Client) CreateView(view timeplus.View) error {
+	ret := _m.Called(view)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateView")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(timeplus.View) error); ok {
+		r0 = rf(view)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Client_CreateView_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateView'
+type Client_CreateView_Call struct {
+	*mock.Call
+}
+
+// CreateView is a helper method to define mock.On call
+//   - view timeplus.View
+func (_e *Client_Expecter) CreateView(view interface{}) *Client_CreateView_Call {
+	return &Client_CreateView_Call{Call: _e.mock.On("CreateView", view)}
+}
+
+func (_c *Client_CreateView_Call) Run(run func(view timeplus.View)) *Client_CreateView_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(timeplus.View))
+	})
+	return _c
+}
+
+func (_c *Client_CreateView_Call) Return(_a0 error) *Client_CreateView_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Client_CreateView_Call) RunAndReturn(run func(timeplus.View) error) *Client_CreateView_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteStream provides a mock function with given fields: streamName
+func (_m *Client) DeleteStream(streamName string) error {
+	ret := _m.Called(streamName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteStream")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(streamName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Client_DeleteStream_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteStream'
+type Client_DeleteStream_Call struct {
+	*mock.Call
+}
+
+// DeleteStream is a helper method to define mock.On call
+//   - streamName string
+func (_e *Client_Expecter) DeleteStream(streamName interface{}) *Client_DeleteStream_Call {
+	return &Client_DeleteStream_Call{Call: _e.mock.On("DeleteStream", streamName)}
+}
+
+func (_c *Client_DeleteStream_Call) Run(run func(streamName string)) *Client_DeleteStream_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *Client_DeleteStream_Call) Return(_a0 error) *Client_DeleteStream_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Client_DeleteStream_Call) RunAndReturn(run func(string) error) *Client_DeleteStream_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ExecSQL provides a mock function with given fields: sql, timeout
+func (_m *Client) ExecSQL(sql string, timeout int) (*timeplus.QueryResult, error) {
+	ret := _m.Called(sql, timeout)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExecSQL")
+	}
+
+	var r0 *timeplus.QueryResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int) (*timeplus.QueryResult, error)); ok {
+		return rf(sql, timeout)
+	}
+	if rf, ok := ret.Get(0).(func(string, int) *timeplus.QueryResult); ok {
+		r0 = rf(sql, timeout)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*timeplus.QueryResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(sql, timeout)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Client_ExecSQL_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ExecSQL'
+type Client_ExecSQL_Call struct {
+	*mock.Call
+}
+
+// ExecSQL is a helper method to define mock.On call
+//   - sql string
+//   - timeout int
+func (_e *Client_Expecter) ExecSQL(sql interface{}, timeout interface{}) *Client_ExecSQL_Call {
+	return &Client_ExecSQL_Call{Call: _e.mock.On("ExecSQL", sql, timeout)}
+}
+
+func (_c *Client_ExecSQL_Call) Run(run func(sql string, timeout int)) *Client_ExecSQL_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *Client_ExecSQL_Call) Return(_a0 *timeplus.QueryResult, _a1 error) *Client_ExecSQL_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Client_ExecSQL_Call) RunAndReturn(run func(string, int) (*timeplus.QueryResult, error)) *Client_ExecSQL_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ExistStream provides a mock function with given fields: name
+func (_m *Client) ExistStream(name string) bool {
+	ret := _m.Called(name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExistStream")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(name)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// Client_ExistStream_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ExistStream'
+type Client_ExistStream_Call struct {
+	*mock.Call
+}
+
+// ExistStream is a helper method to define mock.On call
+//   - name string
+func (_e *Client_Expecter) ExistStream(name interface{}) *Client_ExistStream_Call {
+	return &Client_ExistStream_Call{Call: _e.mock.On("ExistStream", name)}
+}
+
+func (_c *Client_ExistStream_Call) Run(run func(name string)) *Client_ExistStream_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *Client_ExistStream_Call) Return(_a0 bool) *Client_ExistStream_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Client_ExistStream_Call) RunAndReturn(run func(string) bool) *Client_ExistStream_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ExistView provides a mock function with given fields: name
+func (_m *Client) ExistView(name string) bool {
+	ret := _m.Called(name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExistView")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(name)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// Client_ExistView_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ExistView'
+type Client_ExistView_Call struct {
+	*mock.Call
+}
+
+// ExistView is a helper method to define mock.On call
+//   - name string
+func (_e *Client_Expecter) ExistView(name interface{}) *Client_ExistView_Call {
+	return &Client_ExistView_Call{Call: _e.mock.On("ExistView", name)}
+}
+
+func (_c *Client_ExistView_Call) Run(run func(name string)) *Client_ExistView_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *Client_ExistView_Call) Return(_a0 bool) *Client_ExistView_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Client_ExistView_Call) RunAndReturn(run func(string) bool) *Client_ExistView_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetStream provides a mock function with given fields: name
+func (_m *Client) GetStream(name string) (*timeplus.StreamDef, error) {
+	ret := _m.Called(name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetStream")
+	}
+
+	var r0 *timeplus.StreamDef
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*timeplus.StreamDef, error)); ok {
+		return rf(name)
+	}
+	if rf, ok := ret.Get(0).(func(string) *timeplus.StreamDef); ok {
+		r0 = rf(name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*timeplus.StreamDef)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Client_GetStream_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetStream'
+type Client_GetStream_Call struct {
+	*mock.Call
+}
+
+// GetStream is a helper method to define mock.On call
+//   - name string
+func (_e *Client_Expecter) GetStream(name interface{}) *Client_GetStream_Call {
+	return &Client_GetStream_Call{Call: _e.mock.On("GetStream", name)}
+}
+
+func (_c *Client_GetStream_Call) Run(run func(name string)) *Client_GetStream_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *Client_GetStream_Call) Return(_a0 *timeplus.StreamDef, _a1 error) *Client_GetStream_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Client_GetStream_Call) RunAndReturn(run func(string) (*timeplus.StreamDef, error)) *Client_GetStream_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// InsertData provides a mock function with given fields: data
+func (_m *Client) InsertData(data *timeplus.IngestPayload) error {
+	ret := _m.Called(data)
+
+	if len(ret) == 0 {
+		panic("no return value specified for InsertData")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*timeplus.IngestPayload) error); ok {
+		r0 = rf(data)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Client_InsertData_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'InsertData'
+type Client_InsertData_Call struct {
+	*mock.Call
+}
+
+// InsertData is a helper method to define mock.On call
+//   - data *timeplus.IngestPayload
+func (_e *Client_Expecter) InsertData(data interface{}) *Client_InsertData_Call {
+	return &Client_InsertData_Call{Call: _e.mock.On("InsertData", data)}
+}
+
+func (_c *Client_InsertData_Call) Run(run func(data *timeplus.IngestPayload)) *Client_InsertData_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*timeplus.IngestPayload))
+	})
+	return _c
+}
+
+func (_c *Client_InsertData_Call) Return(_a0 error) *Client_InsertData_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Client_InsertData_Call) RunAndReturn(run func(*timeplus.IngestPayload) error) *Client_InsertData_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListStream provides a mock function with no fields
+func (_m *Client) ListStream() ([]timeplus.StreamDef, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListStream")
+	}
+
+	var r0 []timeplus.StreamDef
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]timeplus.StreamDef, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []timeplus.StreamDef); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]timeplus.StreamDef)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Client_ListStream_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListStream'
+type Client_ListStream_Call struct {
+	*mock.Call
+}
+
+// ListStream is a helper method to define mock.On call
+func (_e *Client_Expecter) ListStream() *Client_ListStream_Call {
+	return &Client_ListStream_Call{Call: _e.mock.On("ListStream")}
+}
+
+func (_c *Client_ListStream_Call) Run(run func()) *Client_ListStream_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *Client_ListStream_Call) Return(_a0 []timeplus.StreamDef, _a1 error) *Client_ListStream_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Client_ListStream_Call) RunAndReturn(run func() ([]timeplus.StreamDef, error)) *Client_ListStream_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListView provides a mock function with no fields
+func (_m *Client) ListView() ([]timeplus.View, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListView")
+	}
+
+	var r0 []timeplus.View
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]timeplus.View, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []timeplus.View); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]timeplus.View)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Client_ListView_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListView'
+type Client_ListView_Call struct {
+	*mock.Call
+}
+
+// ListView is a helper method to define mock.On call
+func (_e *Client_Expecter) ListView() *Client_ListView_Call {
+	return &Client_ListView_Call{Call: _e.mock.On("ListView")}
+}
+
+func (_c *Client_ListView_Call) Run(run func()) *Client_ListView_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *Client_ListView_Call) Return(_a0 []timeplus.View, _a1 error) *Client_ListView_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Client_ListView_Call) RunAndReturn(run func() ([]timeplus.View, error)) *Client_ListView_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// QueryStream provides a mock function with given fields: sql, batchCount, batchBufferTime
+func (_m *Client) QueryStream(sql string, batchCount int, batchBufferTime int) (*timeplus.QueryResultStream, error) {
+	ret := _m.Called(sql, batchCount, batchBufferTime)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QueryStream")
+	}
+
+	var r0 *timeplus.QueryResultStream
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int, int) (*timeplus.QueryResultStream, error)); ok {
+		return rf(sql, batchCount, batchBufferTime)
+	}
+	if rf, ok := ret.Get(0).(func(string, int, int) *timeplus.QueryResultStream); ok {
+		r0 = rf(sql, batchCount, batchBufferTime)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*timeplus.QueryResultStream)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int, int) error); ok {
+		r1 = rf(sql, batchCount, batchBufferTime)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Client_QueryStream_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'QueryStream'
+type Client_QueryStream_Call struct {
+	*mock.Call
+}
+
+// QueryStream is a helper method to define mock.On call
+//   - sql string
+//   - batchCount int
+//   - batchBufferTime int
+func (_e *Client_Expecter) QueryStream(sql interface{}, batchCount interface{}, batchBufferTime interface{}) *Client_QueryStream_Call {
+	return &Client_QueryStream_Call{Call: _e.mock.On("QueryStream", sql, batchCount, batchBufferTime)}
+}
+
+func (_c *Client_QueryStream_Call) Run(run func(sql string, batchCount int, batchBufferTime int)) *Client_QueryStream_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(int), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *Client_QueryStream_Call) Return(_a0 *timeplus.QueryResultStream, _a1 error) *Client_QueryStream_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Client_QueryStream_Call) RunAndReturn(run func(string, int, int) (*timeplus.QueryResultStream, error)) *Client_QueryStream_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// QueryStreamWithArgs provides a mock function with given fields: sql, batchCount, batchBufferTime, args
+func (_m *Client) QueryStreamWithArgs(sql string, batchCount int, batchBufferTime int, args ...any) (*timeplus.QueryResultStream, error) {
+	var _ca []interface{}
+	_ca = append(_ca, sql, batchCount, batchBufferTime)
+	_ca = append(_ca, args...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QueryStreamWithArgs")
+	}
+
+	var r0 *timeplus.QueryResultStream
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int, int, ...any) (*timeplus.QueryResultStream, error)); ok {
+		return rf(sql, batchCount, batchBufferTime, args...)
+	}
+	if rf, ok := ret.Get(0).(func(string, int, int, ...any) *timeplus.QueryResultStream); ok {
+		r0 = rf(sql, batchCount, batchBufferTime, args...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*timeplus.QueryResultStream)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int, int, ...any) error); ok {
+		r1 = rf(sql, batchCount, batchBufferTime, args...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Client_QueryStreamWithArgs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'QueryStreamWithArgs'
+type Client_QueryStreamWithArgs_Call struct {
+	*mock.Call
+}
+
+// QueryStreamWithArgs is a helper method to define mock.On call
+//   - sql string
+//   - batchCount int
+//   - batchBufferTime int
+//   - args ...any
+func (_e *Client_Expecter) QueryStreamWithArgs(sql interface{}, batchCount interface{}, batchBufferTime interface{}, args ...interface{}) *Client_QueryStreamWithArgs_Call {
+	return &Client_QueryStreamWithArgs_Call{Call: _e.mock.On("QueryStreamWithArgs",
+		append([]interface{}{sql, batchCount, batchBufferTime}, args...)...)}
+}
+
+func (_c *Client_QueryStreamWithArgs_Call) Run(run func(sql string, batchCount int, batchBufferTime int, args ...any)) *Client_QueryStreamWithArgs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]any, len(args)-3)
+		for i, a := range args[3:] {
+			if a != nil {
+				variadicArgs[i] = a.(any)
+			}
+		}
+		run(args[0].(string), args[1].(int), args[2].(int), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *Client_QueryStreamWithArgs_Call) Return(_a0 *timeplus.QueryResultStream, _a1 error) *Client_QueryStreamWithArgs_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Client_QueryStreamWithArgs_Call) RunAndReturn(run func(string, int, int, ...any) (*timeplus.QueryResultStream, error)) *Client_QueryStreamWithArgs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// QueryStreamWithOptions provides a mock function with given fields: sql, opts
+func (_m *Client) QueryStreamWithOptions(sql string, opts timeplus.QueryOptions) (*timeplus.QueryResultStream, error) {
+	ret := _m.Called(sql, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QueryStreamWithOptions")
+	}
+
+	var r0 *timeplus.QueryResultStream
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, timeplus.QueryOptions) (*timeplus.QueryResultStream, error)); ok {
+		return rf(sql, opts)
+	}
+	if rf, ok := ret.Get(0).(func(string, timeplus.QueryOptions) *timeplus.QueryResultStream); ok {
+		r0 = rf(sql, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*timeplus.QueryResultStream)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, timeplus.QueryOptions) error); ok {
+		r1 = rf(sql, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Client_QueryStreamWithOptions_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'QueryStreamWithOptions'
+type Client_QueryStreamWithOptions_Call struct {
+	*mock.Call
+}
+
+// QueryStreamWithOptions is a helper method to define mock.On call
+//   - sql string
+//   - opts timeplus.QueryOptions
+func (_e *Client_Expecter) QueryStreamWithOptions(sql interface{}, opts interface{}) *Client_QueryStreamWithOptions_Call {
+	return &Client_QueryStreamWithOptions_Call{Call: _e.mock.On("QueryStreamWithOptions", sql, opts)}
+}
+
+func (_c *Client_QueryStreamWithOptions_Call) Run(run func(sql string, opts timeplus.QueryOptions)) *Client_QueryStreamWithOptions_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(timeplus.QueryOptions))
+	})
+	return _c
+}
+
+func (_c *Client_QueryStreamWithOptions_Call) Return(_a0 *timeplus.QueryResultStream, _a1 error) *Client_QueryStreamWithOptions_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Client_QueryStreamWithOptions_Call) RunAndReturn(run func(string, timeplus.QueryOptions) (*timeplus.QueryResultStream, error)) *Client_QueryStreamWithOptions_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewClient creates a new instance of Client. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewClient(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Client {
+	mock := &Client{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
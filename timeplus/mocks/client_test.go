@@ -0,0 +1,41 @@
+package mocks_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/timeplus-io/go-client/metrics"
+	"github.com/timeplus-io/go-client/timeplus"
+	"github.com/timeplus-io/go-client/timeplus/mocks"
+)
+
+func TestMetricWithMockClient(t *testing.T) {
+	client := mocks.NewClient(t)
+	client.EXPECT().ExistStream("_tp_metric_cpu").Return(false)
+	client.EXPECT().CreateStream(mock.MatchedBy(func(def timeplus.StreamDef) bool {
+		return def.Name == "_tp_metric_cpu" && len(def.Columns) == metrics.NumberOfBaseFields+2
+	})).Return(nil)
+	client.EXPECT().InsertData(mock.Anything).Return(errors.New("unavailable")).Once()
+
+	failures := make([]error, 0)
+	opts := metrics.DefaultMetricsOptions()
+	opts.ErrorHandler = func(err error) { failures = append(failures, err) }
+
+	m, err := metrics.NewMetricsWithOptions("cpu", []string{"host"}, []string{"value"}, client, time.Hour, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Observe("app", "cpu", []any{"a"}, []any{1}, nil)
+	m.Close(context.Background())
+
+	if len(failures) != 1 {
+		t.Errorf("expected the failed ingest to be reported, got %v", failures)
+	}
+	if stats := m.Stats(); stats.FailedFlushes != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,19 @@
+module github.com/timeplus-io/go-client/timeplus/mocks
+
+go 1.21
+
+require (
+	github.com/stretchr/testify v1.11.1
+	github.com/timeplus-io/go-client v0.0.0-20261019171749-8818670f452c
+)
+
+require (
+	github.com/cenkalti/backoff/v4 v4.0.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/emirpasic/gods v1.12.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/reactivex/rxgo/v2 v2.5.0 // indirect
+	github.com/stretchr/objx v0.5.2 // indirect
+	github.com/teivah/onecontext v0.0.0-20200513185103-40f981bfd775 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,81 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package mocks
+
+import (
+	mock "github.com/stretchr/testify/mock"
+	timeplus "github.com/timeplus-io/go-client/timeplus"
+)
+
+// Ingester is an autogenerated mock type for the Ingester type
+type Ingester struct {
+	mock.Mock
+}
+
+type Ingester_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Ingester) EXPECT() *Ingester_Expecter {
+	return &Ingester_Expecter{mock: &_m.Mock}
+}
+
+// InsertData provides a mock function with given fields: data
+func (_m *Ingester) InsertData(data *timeplus.IngestPayload) error {
+	ret := _m.Called(data)
+
+	if len(ret) == 0 {
+		panic("no return value specified for InsertData")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*timeplus.IngestPayload) error); ok {
+		r0 = rf(data)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Ingester_InsertData_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'InsertData'
+type Ingester_InsertData_Call struct {
+	*mock.Call
+}
+
+// InsertData is a helper method to define mock.On call
+//   - data *timeplus.IngestPayload
+func (_e *Ingester_Expecter) InsertData(data interface{}) *Ingester_InsertData_Call {
+	return &Ingester_InsertData_Call{Call: _e.mock.On("InsertData", data)}
+}
+
+func (_c *Ingester_InsertData_Call) Run(run func(data *timeplus.IngestPayload)) *Ingester_InsertData_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*timeplus.IngestPayload))
+	})
+	return _c
+}
+
+func (_c *Ingester_InsertData_Call) Return(_a0 error) *Ingester_InsertData_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Ingester_InsertData_Call) RunAndReturn(run func(*timeplus.IngestPayload) error) *Ingester_InsertData_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewIngester creates a new instance of Ingester. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewIngester(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Ingester {
+	mock := &Ingester{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,284 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package mocks
+
+import (
+	mock "github.com/stretchr/testify/mock"
+	timeplus "github.com/timeplus-io/go-client/timeplus"
+)
+
+// Querier is an autogenerated mock type for the Querier type
+type Querier struct {
+	mock.Mock
+}
+
+type Querier_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *Querier) EXPECT() *Querier_Expecter {
+	return &Querier_Expecter{mock: &_m.Mock}
+}
+
+// ExecSQL provides a mock function with given fields: sql, timeout
+func (_m *Querier) ExecSQL(sql string, timeout int) (*timeplus.QueryResult, error) {
+	ret := _m.Called(sql, timeout)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExecSQL")
+	}
+
+	var r0 *timeplus.QueryResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int) (*timeplus.QueryResult, error)); ok {
+		return rf(sql, timeout)
+	}
+	if rf, ok := ret.Get(0).(func(string, int) *timeplus.QueryResult); ok {
+		r0 = rf(sql, timeout)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*timeplus.QueryResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(sql, timeout)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Querier_ExecSQL_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ExecSQL'
+type Querier_ExecSQL_Call struct {
+	*mock.Call
+}
+
+// ExecSQL is a helper method to define mock.On call
+//   - sql string
+//   - timeout int
+func (_e *Querier_Expecter) ExecSQL(sql interface{}, timeout interface{}) *Querier_ExecSQL_Call {
+	return &Querier_ExecSQL_Call{Call: _e.mock.On("ExecSQL", sql, timeout)}
+}
+
+func (_c *Querier_ExecSQL_Call) Run(run func(sql string, timeout int)) *Querier_ExecSQL_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *Querier_ExecSQL_Call) Return(_a0 *timeplus.QueryResult, _a1 error) *Querier_ExecSQL_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Querier_ExecSQL_Call) RunAndReturn(run func(string, int) (*timeplus.QueryResult, error)) *Querier_ExecSQL_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// QueryStream provides a mock function with given fields: sql, batchCount, batchBufferTime
+func (_m *Querier) QueryStream(sql string, batchCount int, batchBufferTime int) (*timeplus.QueryResultStream, error) {
+	ret := _m.Called(sql, batchCount, batchBufferTime)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QueryStream")
+	}
+
+	var r0 *timeplus.QueryResultStream
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int, int) (*timeplus.QueryResultStream, error)); ok {
+		return rf(sql, batchCount, batchBufferTime)
+	}
+	if rf, ok := ret.Get(0).(func(string, int, int) *timeplus.QueryResultStream); ok {
+		r0 = rf(sql, batchCount, batchBufferTime)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*timeplus.QueryResultStream)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int, int) error); ok {
+		r1 = rf(sql, batchCount, batchBufferTime)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Querier_QueryStream_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'QueryStream'
+type Querier_QueryStream_Call struct {
+	*mock.Call
+}
+
+// QueryStream is a helper method to define mock.On call
+//   - sql string
+//   - batchCount int
+//   - batchBufferTime int
+func (_e *Querier_Expecter) QueryStream(sql interface{}, batchCount interface{}, batchBufferTime interface{}) *Querier_QueryStream_Call {
+	return &Querier_QueryStream_Call{Call: _e.mock.On("QueryStream", sql, batchCount, batchBufferTime)}
+}
+
+func (_c *Querier_QueryStream_Call) Run(run func(sql string, batchCount int, batchBufferTime int)) *Querier_QueryStream_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(int), args[2].(int))
+	})
+	return _c
+}
+
+func (_c *Querier_QueryStream_Call) Return(_a0 *timeplus.QueryResultStream, _a1 error) *Querier_QueryStream_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Querier_QueryStream_Call) RunAndReturn(run func(string, int, int) (*timeplus.QueryResultStream, error)) *Querier_QueryStream_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// QueryStreamWithArgs provides a mock function with given fields: sql, batchCount, batchBufferTime, args
+func (_m *Querier) QueryStreamWithArgs(sql string, batchCount int, batchBufferTime int, args ...any) (*timeplus.QueryResultStream, error) {
+	var _ca []interface{}
+	_ca = append(_ca, sql, batchCount, batchBufferTime)
+	_ca = append(_ca, args...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QueryStreamWithArgs")
+	}
+
+	var r0 *timeplus.QueryResultStream
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int, int, ...any) (*timeplus.QueryResultStream, error)); ok {
+		return rf(sql, batchCount, batchBufferTime, args...)
+	}
+	if rf, ok := ret.Get(0).(func(string, int, int, ...any) *timeplus.QueryResultStream); ok {
+		r0 = rf(sql, batchCount, batchBufferTime, args...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*timeplus.QueryResultStream)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int, int, ...any) error); ok {
+		r1 = rf(sql, batchCount, batchBufferTime, args...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Querier_QueryStreamWithArgs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'QueryStreamWithArgs'
+type Querier_QueryStreamWithArgs_Call struct {
+	*mock.Call
+}
+
+// QueryStreamWithArgs is a helper method to define mock.On call
+//   - sql string
+//   - batchCount int
+//   - batchBufferTime int
+//   - args ...any
+func (_e *Querier_Expecter) QueryStreamWithArgs(sql interface{}, batchCount interface{}, batchBufferTime interface{}, args ...interface{}) *Querier_QueryStreamWithArgs_Call {
+	return &Querier_QueryStreamWithArgs_Call{Call: _e.mock.On("QueryStreamWithArgs",
+		append([]interface{}{sql, batchCount, batchBufferTime}, args...)...)}
+}
+
+func (_c *Querier_QueryStreamWithArgs_Call) Run(run func(sql string, batchCount int, batchBufferTime int, args ...any)) *Querier_QueryStreamWithArgs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		variadicArgs := make([]any, len(args)-3)
+		for i, a := range args[3:] {
+			if a != nil {
+				variadicArgs[i] = a.(any)
+			}
+		}
+		run(args[0].(string), args[1].(int), args[2].(int), variadicArgs...)
+	})
+	return _c
+}
+
+func (_c *Querier_QueryStreamWithArgs_Call) Return(_a0 *timeplus.QueryResultStream, _a1 error) *Querier_QueryStreamWithArgs_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Querier_QueryStreamWithArgs_Call) RunAndReturn(run func(string, int, int, ...any) (*timeplus.QueryResultStream, error)) *Querier_QueryStreamWithArgs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// QueryStreamWithOptions provides a mock function with given fields: sql, opts
+func (_m *Querier) QueryStreamWithOptions(sql string, opts timeplus.QueryOptions) (*timeplus.QueryResultStream, error) {
+	ret := _m.Called(sql, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QueryStreamWithOptions")
+	}
+
+	var r0 *timeplus.QueryResultStream
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, timeplus.QueryOptions) (*timeplus.QueryResultStream, error)); ok {
+		return rf(sql, opts)
+	}
+	if rf, ok := ret.Get(0).(func(string, timeplus.QueryOptions) *timeplus.QueryResultStream); ok {
+		r0 = rf(sql, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*timeplus.QueryResultStream)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, timeplus.QueryOptions) error); ok {
+		r1 = rf(sql, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Querier_QueryStreamWithOptions_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'QueryStreamWithOptions'
+type Querier_QueryStreamWithOptions_Call struct {
+	*mock.Call
+}
+
+// QueryStreamWithOptions is a helper method to define mock.On call
+//   - sql string
+//   - opts timeplus.QueryOptions
+func (_e *Querier_Expecter) QueryStreamWithOptions(sql interface{}, opts interface{}) *Querier_QueryStreamWithOptions_Call {
+	return &Querier_QueryStreamWithOptions_Call{Call: _e.mock.On("QueryStreamWithOptions", sql, opts)}
+}
+
+func (_c *Querier_QueryStreamWithOptions_Call) Run(run func(sql string, opts timeplus.QueryOptions)) *Querier_QueryStreamWithOptions_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(timeplus.QueryOptions))
+	})
+	return _c
+}
+
+func (_c *Querier_QueryStreamWithOptions_Call) Return(_a0 *timeplus.QueryResultStream, _a1 error) *Querier_QueryStreamWithOptions_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Querier_QueryStreamWithOptions_Call) RunAndReturn(run func(string, timeplus.QueryOptions) (*timeplus.QueryResultStream, error)) *Querier_QueryStreamWithOptions_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewQuerier creates a new instance of Querier. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewQuerier(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Querier {
+	mock := &Querier{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,94 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package mocks
+
+import (
+	mock "github.com/stretchr/testify/mock"
+	timeplus "github.com/timeplus-io/go-client/timeplus"
+)
+
+// SQLExecutor is an autogenerated mock type for the SQLExecutor type
+type SQLExecutor struct {
+	mock.Mock
+}
+
+type SQLExecutor_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *SQLExecutor) EXPECT() *SQLExecutor_Expecter {
+	return &SQLExecutor_Expecter{mock: &_m.Mock}
+}
+
+// ExecSQL provides a mock function with given fields: sql, timeout
+func (_m *SQLExecutor) ExecSQL(sql string, timeout int) (*timeplus.QueryResult, error) {
+	ret := _m.Called(sql, timeout)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExecSQL")
+	}
+
+	var r0 *timeplus.QueryResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int) (*timeplus.QueryResult, error)); ok {
+		return rf(sql, timeout)
+	}
+	if rf, ok := ret.Get(0).(func(string, int) *timeplus.QueryResult); ok {
+		r0 = rf(sql, timeout)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*timeplus.QueryResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(sql, timeout)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SQLExecutor_ExecSQL_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ExecSQL'
+type SQLExecutor_ExecSQL_Call struct {
+	*mock.Call
+}
+
+// ExecSQL is a helper method to define mock.On call
+//   - sql string
+//   - timeout int
+func (_e *SQLExecutor_Expecter) ExecSQL(sql interface{}, timeout interface{}) *SQLExecutor_ExecSQL_Call {
+	return &SQLExecutor_ExecSQL_Call{Call: _e.mock.On("ExecSQL", sql, timeout)}
+}
+
+func (_c *SQLExecutor_ExecSQL_Call) Run(run func(sql string, timeout int)) *SQLExecutor_ExecSQL_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(int))
+	})
+	return _c
+}
+
+func (_c *SQLExecutor_ExecSQL_Call) Return(_a0 *timeplus.QueryResult, _a1 error) *SQLExecutor_ExecSQL_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *SQLExecutor_ExecSQL_Call) RunAndReturn(run func(string, int) (*timeplus.QueryResult, error)) *SQLExecutor_ExecSQL_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewSQLExecutor creates a new instance of SQLExecutor. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewSQLExecutor(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *SQLExecutor {
+	mock := &SQLExecutor{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,483 @@
+// Code generated by mockery. DO NOT EDIT.
+
+package mocks
+
+import (
+	mock "github.com/stretchr/testify/mock"
+	timeplus "github.com/timeplus-io/go-client/timeplus"
+)
+
+// StreamWriter is an autogenerated mock type for the StreamWriter type
+type StreamWriter struct {
+	mock.Mock
+}
+
+type StreamWriter_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *StreamWriter) EXPECT() *StreamWriter_Expecter {
+	return &StreamWriter_Expecter{mock: &_m.Mock}
+}
+
+// CreateStream provides a mock function with given fields: streamDef
+func (_m *StreamWriter) CreateStream(streamDef timeplus.StreamDef) error {
+	ret := _m.Called(streamDef)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateStream")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(timeplus.StreamDef) error); ok {
+		r0 = rf(streamDef)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// StreamWriter_CreateStream_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateStream'
+type StreamWriter_CreateStream_Call struct {
+	*mock.Call
+}
+
+// CreateStream is a helper method to define mock.On call
+//   - streamDef timeplus.StreamDef
+func (_e *StreamWriter_Expecter) CreateStream(streamDef interface{}) *StreamWriter_CreateStream_Call {
+	return &StreamWriter_CreateStream_Call{Call: _e.mock.On("CreateStream", streamDef)}
+}
+
+func (_c *StreamWriter_CreateStream_Call) Run(run func(streamDef timeplus.StreamDef)) *StreamWriter_CreateStream_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(timeplus.StreamDef))
+	})
+	return _c
+}
+
+func (_c *StreamWriter_CreateStream_Call) Return(_a0 error) *StreamWriter_CreateStream_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *StreamWriter_CreateStream_Call) RunAndReturn(run func(timeplus.StreamDef) error) *StreamWriter_CreateStream_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CreateView provides a mock function with given fields: view
+func (_m *StreamWriter) CreateView(view timeplus.View) error {
+	ret := _m.Called(view)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateView")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(timeplus.View) error); ok {
+		r0 = rf(view)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// StreamWriter_CreateView_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CreateView'
+type StreamWriter_CreateView_Call struct {
+	*mock.Call
+}
+
+// CreateView is a helper method to define mock.On call
+//   - view timeplus.View
+func (_e *StreamWriter_Expecter) CreateView(view interface{}) *StreamWriter_CreateView_Call {
+	return &StreamWriter_CreateView_Call{Call: _e.mock.On("CreateView", view)}
+}
+
+func (_c *StreamWriter_CreateView_Call) Run(run func(view timeplus.View)) *StreamWriter_CreateView_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(timeplus.View))
+	})
+	return _c
+}
+
+func (_c *StreamWriter_CreateView_Call) Return(_a0 error) *StreamWriter_CreateView_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *StreamWriter_CreateView_Call) RunAndReturn(run func(timeplus.View) error) *StreamWriter_CreateView_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DeleteStream provides a mock function with given fields: streamName
+func (_m *StreamWriter) DeleteStream(streamName string) error {
+	ret := _m.Called(streamName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteStream")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(streamName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// StreamWriter_DeleteStream_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteStream'
+type StreamWriter_DeleteStream_Call struct {
+	*mock.Call
+}
+
+// DeleteStream is a helper method to define mock.On call
+//   - streamName string
+func (_e *StreamWriter_Expecter) DeleteStream(streamName interface{}) *StreamWriter_DeleteStream_Call {
+	return &StreamWriter_DeleteStream_Call{Call: _e.mock.On("DeleteStream", streamName)}
+}
+
+func (_c *StreamWriter_DeleteStream_Call) Run(run func(streamName string)) *StreamWriter_DeleteStream_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *StreamWriter_DeleteStream_Call) Return(_a0 error) *StreamWriter_DeleteStream_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *StreamWriter_DeleteStream_Call) RunAndReturn(run func(string) error) *StreamWriter_DeleteStream_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ExistStream provides a mock function with given fields: name
+func (_m *StreamWriter) ExistStream(name string) bool {
+	ret := _m.Called(name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExistStream")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(name)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// StreamWriter_ExistStream_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ExistStream'
+type StreamWriter_ExistStream_Call struct {
+	*mock.Call
+}
+
+// ExistStream is a helper method to define mock.On call
+//   - name string
+func (_e *StreamWriter_Expecter) ExistStream(name interface{}) *StreamWriter_ExistStream_Call {
+	return &StreamWriter_ExistStream_Call{Call: _e.mock.On("ExistStream", name)}
+}
+
+func (_c *StreamWriter_ExistStream_Call) Run(run func(name string)) *StreamWriter_ExistStream_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *StreamWriter_ExistStream_Call) Return(_a0 bool) *StreamWriter_ExistStream_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *StreamWriter_ExistStream_Call) RunAndReturn(run func(string) bool) *StreamWriter_ExistStream_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ExistView provides a mock function with given fields: name
+func (_m *StreamWriter) ExistView(name string) bool {
+	ret := _m.Called(name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExistView")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(name)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// StreamWriter_ExistView_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ExistView'
+type StreamWriter_ExistView_Call struct {
+	*mock.Call
+}
+
+// ExistView is a helper method to define mock.On call
+//   - name string
+func (_e *StreamWriter_Expecter) ExistView(name interface{}) *StreamWriter_ExistView_Call {
+	return &StreamWriter_ExistView_Call{Call: _e.mock.On("ExistView", name)}
+}
+
+func (_c *StreamWriter_ExistView_Call) Run(run func(name string)) *StreamWriter_ExistView_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *StreamWriter_ExistView_Call) Return(_a0 bool) *StreamWriter_ExistView_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *StreamWriter_ExistView_Call) RunAndReturn(run func(string) bool) *StreamWriter_ExistView_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetStream provides a mock function with given fields: name
+func (_m *StreamWriter) GetStream(name string) (*timeplus.StreamDef, error) {
+	ret := _m.Called(name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetStream")
+	}
+
+	var r0 *timeplus.StreamDef
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*timeplus.StreamDef, error)); ok {
+		return rf(name)
+	}
+	if rf, ok := ret.Get(0).(func(string) *timeplus.StreamDef); ok {
+		r0 = rf(name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*timeplus.StreamDef)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// StreamWriter_GetStream_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetStream'
+type StreamWriter_GetStream_Call struct {
+	*mock.Call
+}
+
+// GetStream is a helper method to define mock.On call
+//   - name string
+func (_e *StreamWriter_Expecter) GetStream(name interface{}) *StreamWriter_GetStream_Call {
+	return &StreamWriter_GetStream_Call{Call: _e.mock.On("GetStream", name)}
+}
+
+func (_c *StreamWriter_GetStream_Call) Run(run func(name string)) *StreamWriter_GetStream_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *StreamWriter_GetStream_Call) Return(_a0 *timeplus.StreamDef, _a1 error) *StreamWriter_GetStream_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *StreamWriter_GetStream_Call) RunAndReturn(run func(string) (*timeplus.StreamDef, error)) *StreamWriter_GetStream_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// InsertData provides a mock function with given fields: data
+func (_m *StreamWriter) InsertData(data *timeplus.IngestPayload) error {
+	ret := _m.Called(data)
+
+	if len(ret) == 0 {
+		panic("no return value specified for InsertData")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*timeplus.IngestPayload) error); ok {
+		r0 = rf(data)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// StreamWriter_InsertData_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'InsertData'
+type StreamWriter_InsertData_Call struct {
+	*mock.Call
+}
+
+// InsertData is a helper method to define mock.On call
+//   - data *timeplus.IngestPayload
+func (_e *StreamWriter_Expecter) InsertData(data interface{}) *StreamWriter_InsertData_Call {
+	return &StreamWriter_InsertData_Call{Call: _e.mock.On("InsertData", data)}
+}
+
+func (_c *StreamWriter_InsertData_Call) Run(run func(data *timeplus.IngestPayload)) *StreamWriter_InsertData_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*timeplus.IngestPayload))
+	})
+	return _c
+}
+
+func (_c *StreamWriter_InsertData_Call) Return(_a0 error) *StreamWriter_InsertData_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *StreamWriter_InsertData_Call) RunAndReturn(run func(*timeplus.IngestPayload) error) *StreamWriter_InsertData_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListStream provides a mock function with no fields
+func (_m *StreamWriter) ListStream() ([]timeplus.StreamDef, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListStream")
+	}
+
+	var r0 []timeplus.StreamDef
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]timeplus.StreamDef, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []timeplus.StreamDef); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]timeplus.StreamDef)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// StreamWriter_ListStream_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListStream'
+type StreamWriter_ListStream_Call struct {
+	*mock.Call
+}
+
+// ListStream is a helper method to define mock.On call
+func (_e *StreamWriter_Expecter) ListStream() *StreamWriter_ListStream_Call {
+	return &StreamWriter_ListStream_Call{Call: _e.mock.On("ListStream")}
+}
+
+func (_c *StreamWriter_ListStream_Call) Run(run func()) *StreamWriter_ListStream_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *StreamWriter_ListStream_Call) Return(_a0 []timeplus.StreamDef, _a1 error) *StreamWriter_ListStream_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *StreamWriter_ListStream_Call) RunAndReturn(run func() ([]timeplus.StreamDef, error)) *StreamWriter_ListStream_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ListView provides a mock function with no fields
+func (_m *StreamWriter) ListView() ([]timeplus.View, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListView")
+	}
+
+	var r0 []timeplus.View
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]timeplus.View, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []timeplus.View); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]timeplus.View)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// StreamWriter_ListView_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListView'
+type StreamWriter_ListView_Call struct {
+	*mock.Call
+}
+
+// ListView is a helper method to define mock.On call
+func (_e *StreamWriter_Expecter) ListView() *StreamWriter_ListView_Call {
+	return &StreamWriter_ListView_Call{Call: _e.mock.On("ListView")}
+}
+
+func (_c *StreamWriter_ListView_Call) Run(run func()) *StreamWriter_ListView_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *StreamWriter_ListView_Call) Return(_a0 []timeplus.View, _a1 error) *StreamWriter_ListView_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *StreamWriter_ListView_Call) RunAndReturn(run func() ([]timeplus.View, error)) *StreamWriter_ListView_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewStreamWriter creates a new instance of StreamWriter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewStreamWriter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *StreamWriter {
+	mock := &StreamWriter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
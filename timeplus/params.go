@@ -0,0 +1,264 @@
+package timeplus
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const datetime64Format = "2006-01-02 15:04:05.000000000"
+
+// NamedArg is a query parameter referenced in SQL as @name
+type NamedArg struct {
+	Name  string
+	Value any
+}
+
+func Named(name string, value any) NamedArg {
+	return NamedArg{Name: name, Value: value}
+}
+
+// Bind replaces the placeholders in sql with escaped literals of args.
+// Positional placeholders are written as ? and consume the non named args in order,
+// named placeholders are written as @name and are resolved from NamedArg args.
+// Placeholders inside string literals, quoted identifiers and comments are left untouched.
+// The binding happens in the client, the query api of the server does not take parameters,
+// so the values are sent as escaped literals of the sql, see Escape.
+func Bind(sql string, args ...any) (string, error) {
+	positional := make([]any, 0, len(args))
+	named := make(map[string]any)
+	for _, arg := range args {
+		if n, ok := arg.(NamedArg); ok {
+			if !isIdent(n.Name) {
+				return "", fmt.Errorf("invalid parameter name %q", n.Name)
+			}
+			named[n.Name] = n.Value
+		} else {
+			positional = append(positional, arg)
+		}
+	}
+
+	var b strings.Builder
+	b.Grow(len(sql))
+	used := 0
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := skipQuoted(sql, i)
+			b.WriteString(sql[i:end])
+			i = end
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			b.WriteString(sql[i : i+end])
+			i += end
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				end = len(sql) - i
+			} else {
+				end += 4
+			}
+			b.WriteString(sql[i : i+end])
+			i += end
+		case c == '?':
+			if used >= len(positional) {
+				return "", fmt.Errorf("missing value for positional parameter %d", used+1)
+			}
+			literal, err := Escape(positional[used])
+			if err != nil {
+				return "", fmt.Errorf("failed to bind positional parameter %d: %w", used+1, err)
+			}
+			b.WriteString(literal)
+			used++
+			i++
+		case c == '@' && i+1 < len(sql) && isIdentStart(sql[i+1]):
+			end := i + 1
+			for end < len(sql) && isIdentPart(sql[end]) {
+				end++
+			}
+			name := sql[i+1 : end]
+			value, ok := named[name]
+			if !ok {
+				return "", fmt.Errorf("missing value for named parameter @%s", name)
+			}
+			literal, err := Escape(value)
+			if err != nil {
+				return "", fmt.Errorf("failed to bind named parameter @%s: %w", name, err)
+			}
+			b.WriteString(literal)
+			i = end
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+
+	if used != len(positional) {
+		return "", fmt.Errorf("too many positional parameters, expected %d got %d", used, len(positional))
+	}
+	return b.String(), nil
+}
+
+// Escape renders v as a Timeplus SQL literal
+func Escape(v any) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		return QuoteString(val), nil
+	case []byte:
+		return QuoteString(string(val)), nil
+	case time.Time:
+		return fmt.Sprintf("to_datetime64(%s, 9, 'UTC')", QuoteString(val.UTC().Format(datetime64Format))), nil
+	case NamedArg:
+		return "", fmt.Errorf("named parameter @%s cannot be used as a value", val.Name)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return "NULL", nil
+		}
+		return Escape(rv.Elem().Interface())
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return formatFloat(rv.Float(), rv.Type().Bits()), nil
+	case reflect.String:
+		return QuoteString(rv.String()), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return "[]", nil
+		}
+		items := make([]string, rv.Len())
+		for i := range items {
+			item, err := Escape(rv.Index(i).Interface())
+			if err != nil {
+				return "", fmt.Errorf("array element %d: %w", i, err)
+			}
+			items[i] = item
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case reflect.Map:
+		type entry struct{ key, value string }
+		entries := make([]entry, 0, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key, err := Escape(iter.Key().Interface())
+			if err != nil {
+				return "", fmt.Errorf("map key: %w", err)
+			}
+			value, err := Escape(iter.Value().Interface())
+			if err != nil {
+				return "", fmt.Errorf("map value of key %s: %w", key, err)
+			}
+			entries = append(entries, entry{key, value})
+		}
+		// keep the output stable since map iteration order is random
+		sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+		parts := make([]string, 0, len(entries)*2)
+		for _, e := range entries {
+			parts = append(parts, e.key, e.value)
+		}
+		return "map(" + strings.Join(parts, ", ") + ")", nil
+	}
+
+	return "", fmt.Errorf("unsupported parameter type %T", v)
+}
+
+// QuoteString quotes s as a single quoted string literal
+func QuoteString(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\'':
+			b.WriteString(`\'`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case 0:
+			b.WriteString(`\0`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// QuoteIdentifier quotes name with backticks so it can be used as a stream or column name
+func QuoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(strings.ReplaceAll(name, "\\", "\\\\"), "`", "\\`") + "`"
+}
+
+func formatFloat(f float64, bits int) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, bits)
+}
+
+// skipQuoted returns the index right after the quoted section starting at start
+func skipQuoted(sql string, start int) int {
+	quote := sql[start]
+	for i := start + 1; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			i++
+		case quote:
+			// a doubled quote is an escaped quote
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(sql)
+}
+
+// isIdent reports whether s is a plain name such as a parameter name
+func isIdent(s string) bool {
+	if len(s) == 0 || !isIdentStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isIdentPart(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
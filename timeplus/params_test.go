@@ -0,0 +1,80 @@
+package timeplus_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/timeplus-io/go-client/timeplus"
+)
+
+func TestEscape(t *testing.T) {
+	ts := time.Date(2023, 1, 2, 3, 4, 5, 6, time.FixedZone("x", 3600))
+	var nilPtr *int
+	three := 3
+
+	cases := []struct {
+		value    any
+		expected string
+	}{
+		{nil, "NULL"},
+		{nilPtr, "NULL"},
+		{&three, "3"},
+		{"it's", `'it\'s'`},
+		{`a\b`, `'a\\b'`},
+		{"line\nbreak", `'line\nbreak'`},
+		{true, "true"},
+		{int8(-5), "-5"},
+		{uint64(18446744073709551615), "18446744073709551615"},
+		{1.5, "1.5"},
+		{math.NaN(), "nan"},
+		{math.Inf(-1), "-inf"},
+		{ts, "to_datetime64('2023-01-02 02:04:05.000000006', 9, 'UTC')"},
+		{[]string{"a", "b'"}, `['a', 'b\'']`},
+		{[]int(nil), "[]"},
+		{[][]int{{1}, {2, 3}}, "[[1], [2, 3]]"},
+		{map[string]int{"b": 2, "a": 1}, "map('a', 1, 'b', 2)"},
+	}
+
+	for _, c := range cases {
+		result, err := timeplus.Escape(c.value)
+		if err != nil {
+			t.Fatalf("failed to escape %v: %s", c.value, err)
+		}
+		if result != c.expected {
+			t.Errorf("escape %v: expected %s, got %s", c.value, c.expected, result)
+		}
+	}
+
+	if _, err := timeplus.Escape(struct{}{}); err == nil {
+		t.Errorf("expected error for unsupported type")
+	}
+}
+
+func TestBind(t *testing.T) {
+	sql, err := timeplus.Bind(
+		"select * from car_live_data where cid = ? and speed > @speed and note = '?' -- @ignored ?\nand tags = `@col` /* ? */ and x in ?",
+		"c0'1", timeplus.Named("speed", 80), []int{1, 2},
+	)
+	if err != nil {
+		t.Fatalf("failed to bind: %s", err)
+	}
+
+	expected := "select * from car_live_data where cid = 'c0\\'1' and speed > 80 and note = '?' -- @ignored ?\nand tags = `@col` /* ? */ and x in [1, 2]"
+	if sql != expected {
+		t.Errorf("expected %s, got %s", expected, sql)
+	}
+
+	if _, err := timeplus.Bind("select ?, ?", 1); err == nil {
+		t.Errorf("expected error for missing positional parameter")
+	}
+	if _, err := timeplus.Bind("select ?", 1, 2); err == nil {
+		t.Errorf("expected error for unused positional parameter")
+	}
+	if _, err := timeplus.Bind("select @a"); err == nil {
+		t.Errorf("expected error for missing named parameter")
+	}
+	if _, err := timeplus.Bind("select @a", timeplus.Named("a b", 1)); err == nil {
+		t.Errorf("expected error for invalid parameter name")
+	}
+}
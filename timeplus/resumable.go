@@ -0,0 +1,489 @@
+package timeplus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/reactivex/rxgo/v2"
+
+	"github.com/timeplus-io/go-client/utils"
+)
+
+const DefaultResumeTimeColumn = "_tp_time"
+const DefaultResumeInitialBackoff = 1 * time.Second
+const DefaultResumeMaxBackoff = 30 * time.Second
+
+var eventTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+}
+
+// ResumeOptions controls how a resumable query recovers from a dropped connection
+type ResumeOptions struct {
+	// TimeColumn is the event time column used as resume position, it has to be part of the query result
+	TimeColumn string
+	// TimeUnit is the unit of the numeric values of a TimeColumn which is not a datetime, such as the
+	// epoch milliseconds of an int64 column, it defaults to time.Millisecond. The numeric values of
+	// a datetime column are in the precision of the column.
+	TimeUnit time.Duration
+	// SequenceColumn is an optional column increasing with every event, such as _tp_sn,
+	// which is used instead of the event time to drop rows replayed after a reconnect
+	SequenceColumn string
+	// MaxRetries is the number of consecutive failed reconnects before giving up, 0 retries forever
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// OnReconnect is called for every reconnect attempt
+	OnReconnect func(ReconnectEvent)
+	// OnEvent receives the server events other than query result, see QueryOptions
+	OnEvent func(QueryEvent)
+}
+
+type ReconnectEvent struct {
+	Attempt int
+	// Cause is the error which dropped the connection
+	Cause error
+	// SeekTo is the position the query is reissued from, empty if no event was seen yet
+	SeekTo string
+	// Err is nil if the query was reissued successfully
+	Err      error
+	Metadata *QueryInfo
+}
+
+type resumeState struct {
+	// ctx keeps the reconnects on the endpoint of the query when the client has an endpoint pool
+	ctx            context.Context
+	sql            string
+	policy         BatchingPolicy
+	timeColumn     string
+	timeUnit       time.Duration
+	numericUnit    time.Duration
+	sequenceColumn string
+	timeIndex      int
+	sequenceIndex  int
+	onEvent        func(QueryEvent)
+
+	position  string
+	maxTime   time.Time
+	hasTime   bool
+	seenAtMax map[string]struct{}
+	maxSeq    int64
+	hasSeq    bool
+	replaying bool
+}
+
+// QueryStreamResumable runs a streaming query which reconnects when the connection drops.
+// The query is reissued with seek_to set to the latest event time seen so far and the
+// rows replayed by the server are dropped, so the consumer sees each row once
+// as long as events of the same time arrive in the same order.
+// The query ends without reconnecting when the server ends the query or sends an error event, which
+// is delivered as QueryError. Metadata is the QueryInfo of the first query, see LatestMetadata for
+// the query reissued by the last reconnect.
+// ResultStream holds the rows until it is observed, it delivers them to a single observer.
+func (s *TimeplusClient) QueryStreamResumable(sql string, batchCount int, batchBufferTime int, opts ResumeOptions) (*QueryResultStream, error) {
+	if len(opts.TimeColumn) == 0 {
+		opts.TimeColumn = DefaultResumeTimeColumn
+	}
+	if opts.TimeUnit <= 0 {
+		opts.TimeUnit = time.Millisecond
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = DefaultResumeInitialBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultResumeMaxBackoff
+	}
+
+	state := &resumeState{
+		ctx: utils.WithEndpointAffinity(s.context()),
+		sql: sql,
+		policy: BatchingPolicy{
+			Count:  batchCount,
+			TimeMS: batchBufferTime,
+		},
+		timeColumn:     opts.TimeColumn,
+		timeUnit:       opts.TimeUnit,
+		sequenceColumn: opts.SequenceColumn,
+		onEvent:        opts.OnEvent,
+		seenAtMax:      make(map[string]struct{}),
+	}
+
+	conn, err := s.openResumableQuery(state)
+	if err != nil {
+		return nil, err
+	}
+
+	var lock sync.Mutex
+	current := conn
+	ch := make(chan rxgo.Item)
+	ctx, stop := context.WithCancel(s.context())
+
+	metadata := conn.metadata
+	result := &QueryResultStream{
+		Metadata: &metadata,
+		// the rows wait in ch for the first observer instead of being published before it subscribes
+		ResultStream: rxgo.FromChannel(ch),
+		Cancel: func() {
+			stop()
+			lock.Lock()
+			current.close()
+			lock.Unlock()
+		},
+	}
+
+	go func() {
+		defer close(ch)
+		defer func() {
+			lock.Lock()
+			current.close()
+			lock.Unlock()
+		}()
+
+		for {
+			event, err := current.next()
+			if err == nil {
+				if filtered := state.filter(event); len(*filtered) > 0 {
+					if !sendItem(ctx, ch, rxgo.Of(filtered)) {
+						return
+					}
+				}
+				continue
+			}
+
+			if ctx.Err() != nil || err == io.EOF {
+				// the query is cancelled or ended by the server
+				return
+			}
+			var queryErr *QueryError
+			if errors.As(err, &queryErr) {
+				// the server failed the query, running it again would fail the same way
+				sendItem(ctx, ch, rxgo.Error(err))
+				return
+			}
+
+			current.close()
+			next, err := s.reconnect(ctx, state, err, opts)
+			if err != nil {
+				if ctx.Err() == nil {
+					sendItem(ctx, ch, rxgo.Error(err))
+				}
+				return
+			}
+
+			lock.Lock()
+			current = next
+			lock.Unlock()
+			latest := next.metadata
+			result.latest.Store(&latest)
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+
+	return result, nil
+}
+
+func (s *TimeplusClient) reconnect(ctx context.Context, state *resumeState, cause error, opts ResumeOptions) (*queryConn, error) {
+	backoff := opts.InitialBackoff
+	for attempt := 1; opts.MaxRetries == 0 || attempt <= opts.MaxRetries; attempt++ {
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		conn, err := s.openResumableQuery(state)
+		if opts.OnReconnect != nil {
+			event := ReconnectEvent{
+				Attempt: attempt,
+				Cause:   cause,
+				SeekTo:  state.position,
+				Err:     err,
+			}
+			if conn != nil {
+				event.Metadata = &conn.metadata
+			}
+			opts.OnReconnect(event)
+		}
+		if err == nil {
+			return conn, nil
+		}
+
+		backoff *= 2
+		if backoff > opts.MaxBackoff {
+			backoff = opts.MaxBackoff
+		}
+	}
+	return nil, fmt.Errorf("failed to resume query after %d retries: %w", opts.MaxRetries, cause)
+}
+
+func (s *TimeplusClient) openResumableQuery(state *resumeState) (*queryConn, error) {
+	sql := state.sql
+	if len(state.position) > 0 {
+		sql = withSetting(sql, "seek_to", QuoteString(state.position))
+	}
+
+	conn, err := s.openQuery(state.ctx, Query{
+		SQL:    sql,
+		Tags:   []string{},
+		Policy: state.policy,
+	}, state.onEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := state.resolveColumns(conn.metadata.Result.Header); err != nil {
+		conn.close()
+		return nil, err
+	}
+	state.replaying = state.hasTime || state.hasSeq
+	return conn, nil
+}
+
+func (r *resumeState) resolveColumns(header []ColumnDef) error {
+	r.timeIndex = columnIndex(header, r.timeColumn)
+	if r.timeIndex < 0 {
+		return fmt.Errorf("resumable query requires column %s in the query result", r.timeColumn)
+	}
+	r.numericUnit = numericTimeUnit(header[r.timeIndex].Type, r.timeUnit)
+
+	r.sequenceIndex = -1
+	if len(r.sequenceColumn) > 0 {
+		r.sequenceIndex = columnIndex(header, r.sequenceColumn)
+		if r.sequenceIndex < 0 {
+			return fmt.Errorf("resumable query requires column %s in the query result", r.sequenceColumn)
+		}
+	}
+	return nil
+}
+
+// filter drops the rows which have been seen before the reconnect and records the resume position
+func (r *resumeState) filter(event *DataEvent) *DataEvent {
+	result := make(DataEvent, 0, len(*event))
+	for _, row := range *event {
+		if r.accept(row) {
+			result = append(result, row)
+		}
+	}
+	return &result
+}
+
+func (r *resumeState) accept(row []any) bool {
+	if r.timeIndex >= len(row) {
+		return true
+	}
+
+	if r.sequenceIndex >= 0 && r.sequenceIndex < len(row) {
+		if seq, ok := toInt64(row[r.sequenceIndex]); ok {
+			if r.hasSeq && seq <= r.maxSeq {
+				return false
+			}
+			r.maxSeq = seq
+			r.hasSeq = true
+			r.advanceTime(row)
+			return true
+		}
+	}
+
+	eventTime, ok := r.eventTime(row[r.timeIndex])
+	if !ok {
+		return true
+	}
+
+	if r.replaying {
+		if eventTime.Before(r.maxTime) {
+			return false
+		}
+		if eventTime.Equal(r.maxTime) {
+			if _, seen := r.seenAtMax[fingerprint(row)]; seen {
+				return false
+			}
+		} else {
+			r.replaying = false
+		}
+	}
+
+	r.advanceTime(row)
+	return true
+}
+
+func (r *resumeState) advanceTime(row []any) {
+	eventTime, ok := r.eventTime(row[r.timeIndex])
+	if !ok {
+		return
+	}
+
+	if !r.hasTime || eventTime.After(r.maxTime) {
+		r.maxTime = eventTime
+		r.hasTime = true
+		r.seenAtMax = make(map[string]struct{})
+		if raw, isString := row[r.timeIndex].(string); isString {
+			r.position = raw
+		} else {
+			r.position = eventTime.UTC().Format(datetime64Format)
+		}
+	}
+	if eventTime.Equal(r.maxTime) {
+		r.seenAtMax[fingerprint(row)] = struct{}{}
+	}
+}
+
+func columnIndex(header []ColumnDef, name string) int {
+	for index, col := range header {
+		if col.Name == name {
+			return index
+		}
+	}
+	return -1
+}
+
+// eventTime accepts time strings and numbers of the numeric unit of the time column since epoch
+func (r *resumeState) eventTime(v any) (time.Time, bool) {
+	switch val := v.(type) {
+	case string:
+		for _, layout := range eventTimeLayouts {
+			if t, err := time.Parse(layout, val); err == nil {
+				return t, true
+			}
+		}
+	case float64:
+		return time.Unix(0, int64(val*float64(r.numericUnit))), true
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return time.Unix(0, n*int64(r.numericUnit)), true
+		}
+		if f, err := val.Float64(); err == nil {
+			return time.Unix(0, int64(f*float64(r.numericUnit))), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// numericTimeUnit is the unit of the numbers of a column of columnType, a datetime64 counts ticks of its
+// precision and a datetime counts seconds, fallback is used for the other types
+func numericTimeUnit(columnType string, fallback time.Duration) time.Duration {
+	columnType = strings.ToLower(strings.TrimSpace(columnType))
+	if args, ok := strings.CutPrefix(columnType, "datetime64("); ok {
+		precision, _, _ := strings.Cut(strings.TrimSuffix(args, ")"), ",")
+		if scale, err := strconv.Atoi(strings.TrimSpace(precision)); err == nil && scale >= 0 && scale <= 9 {
+			unit := time.Second
+			for i := 0; i < scale; i++ {
+				unit /= 10
+			}
+			return unit
+		}
+		return fallback
+	}
+	if strings.HasPrefix(columnType, "datetime") {
+		return time.Second
+	}
+	return fallback
+}
+
+func toInt64(v any) (int64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return int64(val), true
+	case string:
+		n, err := strconv.ParseInt(val, 10, 64)
+		return n, err == nil
+	case json.Number:
+		n, err := val.Int64()
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func fingerprint(row []any) string {
+	data, _ := json.Marshal(row)
+	return string(data)
+}
+
+// withSetting sets a query setting on sql, replacing the existing value if the setting is already present
+func withSetting(sql string, key string, value string) string {
+	sql = strings.TrimRight(strings.TrimSpace(sql), ";")
+	settingsIndex := lastKeywordIndex(sql, "settings")
+	if settingsIndex < 0 {
+		return fmt.Sprintf("%s SETTINGS %s=%s", sql, key, value)
+	}
+
+	head := sql[:settingsIndex+len("settings")]
+	settings := splitTopLevel(sql[settingsIndex+len("settings"):])
+	replaced := false
+	for i, setting := range settings {
+		name, _, _ := strings.Cut(setting, "=")
+		if strings.EqualFold(strings.TrimSpace(name), key) {
+			settings[i] = fmt.Sprintf("%s=%s", key, value)
+			replaced = true
+		}
+	}
+	if !replaced {
+		settings = append(settings, fmt.Sprintf("%s=%s", key, value))
+	}
+	return head + " " + strings.Join(settings, ", ")
+}
+
+// lastKeywordIndex finds the last occurrence of keyword outside of quotes and parentheses
+func lastKeywordIndex(sql string, keyword string) int {
+	found := -1
+	depth := 0
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(sql, i)
+			continue
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && isIdentStart(c) && (i == 0 || !isIdentPart(sql[i-1])):
+			end := i
+			for end < len(sql) && isIdentPart(sql[end]) {
+				end++
+			}
+			if strings.EqualFold(sql[i:end], keyword) {
+				found = i
+			}
+			i = end
+			continue
+		}
+		i++
+	}
+	return found
+}
+
+// splitTopLevel splits a comma separated list, ignoring commas in quotes and parentheses
+func splitTopLevel(list string) []string {
+	result := make([]string, 0)
+	depth := 0
+	start := 0
+	for i := 0; i < len(list); {
+		switch c := list[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(list, i)
+			continue
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			result = append(result, strings.TrimSpace(list[start:i]))
+			start = i + 1
+		}
+		i++
+	}
+	if last := strings.TrimSpace(list[start:]); len(last) > 0 {
+		result = append(result, last)
+	}
+	return result
+}
@@ -0,0 +1,223 @@
+package timeplus_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timeplus-io/go-client/timeplus"
+	"github.com/timeplus-io/go-client/timeplustest"
+)
+
+const testQueryHeader = `{"id":"q1","result":{"header":[{"name":"_tp_time","type":"datetime64(3)"},{"name":"v","type":"int64"}]}}`
+
+func TestQueryStreamResumable(t *testing.T) {
+	var lock sync.Mutex
+	queries := make([]string, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var query timeplus.Query
+		json.NewDecoder(r.Body).Decode(&query)
+
+		lock.Lock()
+		queries = append(queries, query.SQL)
+		attempt := len(queries)
+		lock.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		header := strings.Replace(testQueryHeader, "q1", fmt.Sprintf("q%d", attempt), 1)
+		fmt.Fprintf(w, "event: query\ndata: %s\n\n", header)
+		if attempt == 1 {
+			fmt.Fprint(w, "data: [[\"2023-01-01 00:00:01.000\", 1], [\"2023-01-01 00:00:02.000\", 2]]\n\n")
+			// drop the connection without ending the response
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+
+		fmt.Fprint(w, "data: [[\"2023-01-01 00:00:02.000\", 2], [\"2023-01-01 00:00:02.000\", 22], [\"2023-01-01 00:00:03.000\", 3]]\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	reconnects := make(chan timeplus.ReconnectEvent, 1)
+	client := timeplus.NewCient(server.URL, "", "key")
+	result, err := client.QueryStreamResumable("select _tp_time, v from s settings query_mode='streaming'", 10, 100, timeplus.ResumeOptions{
+		InitialBackoff: 10 * time.Millisecond,
+		OnReconnect: func(e timeplus.ReconnectEvent) {
+			reconnects <- e
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to query: %s", err)
+	}
+	defer result.Cancel()
+
+	values := make([]float64, 0)
+	for item := range result.ResultStream.Observe() {
+		if item.E != nil {
+			t.Fatalf("unexpected error %s", item.E)
+		}
+		for _, row := range *item.V.(*timeplus.DataEvent) {
+			values = append(values, row[1].(float64))
+		}
+		if len(values) >= 4 {
+			break
+		}
+	}
+
+	expected := []float64{1, 2, 22, 3}
+	if fmt.Sprint(values) != fmt.Sprint(expected) {
+		t.Errorf("expected values %v, got %v", expected, values)
+	}
+	if result.Metadata.ID != "q1" || result.LatestMetadata().ID != "q2" {
+		t.Errorf("expected the metadata of the first and the reissued query, got %s and %s", result.Metadata.ID, result.LatestMetadata().ID)
+	}
+
+	select {
+	case e := <-reconnects:
+		if e.Attempt != 1 || e.Err != nil || e.SeekTo != "2023-01-01 00:00:02.000" {
+			t.Errorf("unexpected reconnect event %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("no reconnect event reported")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if !strings.HasSuffix(queries[1], "settings query_mode='streaming', seek_to='2023-01-01 00:00:02.000'") {
+		t.Errorf("unexpected resumed query %s", queries[1])
+	}
+}
+
+func TestQueryStreamResumableQueryError(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	server.ScriptQuery("from s", timeplustest.QueryScript{
+		Header: []timeplus.ColumnDef{{Name: "_tp_time", Type: "datetime64(3)"}, {Name: "v", Type: "int64"}},
+		Events: []timeplustest.ScriptEvent{
+			timeplustest.Rows([]any{"2023-01-01 00:00:01.000", 1}),
+			timeplustest.Error(62, "syntax error"),
+		},
+	})
+
+	result, err := server.Client().QueryStreamResumable("select _tp_time, v from s", 10, 100, timeplus.ResumeOptions{
+		InitialBackoff: 10 * time.Millisecond,
+		OnReconnect: func(e timeplus.ReconnectEvent) {
+			t.Errorf("unexpected reconnect %+v", e)
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to query: %s", err)
+	}
+	defer result.Cancel()
+
+	var queryErr *timeplus.QueryError
+	for item := range result.ResultStream.Observe() {
+		if item.E != nil {
+			if !errors.As(item.E, &queryErr) {
+				t.Errorf("expected a QueryError, got %s", item.E)
+			}
+			break
+		}
+	}
+	if queryErr == nil || queryErr.Code != 62 {
+		t.Errorf("expected the query to end with the server error, got %v", queryErr)
+	}
+}
+
+func TestQueryStreamResumableEnd(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	server.ScriptQuery("from s", timeplustest.QueryScript{
+		Header: []timeplus.ColumnDef{{Name: "ts", Type: "int64"}, {Name: "v", Type: "int64"}},
+		Events: []timeplustest.ScriptEvent{timeplustest.Rows([]any{1672531201000, 1}, []any{1672531202000, 2})},
+	})
+
+	result, err := server.Client().QueryStreamResumable("select ts, v from s", 10, 100, timeplus.ResumeOptions{
+		TimeColumn:     "ts",
+		InitialBackoff: 10 * time.Millisecond,
+		OnReconnect: func(e timeplus.ReconnectEvent) {
+			t.Errorf("unexpected reconnect %+v", e)
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to query: %s", err)
+	}
+	defer result.Cancel()
+
+	rows := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for item := range result.ResultStream.Observe() {
+			if item.E != nil {
+				t.Errorf("unexpected error %s", item.E)
+				continue
+			}
+			rows += len(*item.V.(*timeplus.DataEvent))
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("query not ended with the server")
+	}
+	if rows != 2 {
+		t.Errorf("expected 2 rows, got %d", rows)
+	}
+}
+
+func TestQueryStreamResumableNumericTime(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	header := []timeplus.ColumnDef{{Name: "_tp_time", Type: "datetime64(6, 'UTC')"}, {Name: "v", Type: "int64"}}
+	server.ScriptQuery("from s", timeplustest.QueryScript{
+		Header: header,
+		Events: []timeplustest.ScriptEvent{timeplustest.Rows([]any{1672531202000000, 1}), timeplustest.Disconnect()},
+		Times:  1,
+	})
+	server.ScriptQuery("from s", timeplustest.QueryScript{
+		Header:   header,
+		Events:   []timeplustest.ScriptEvent{timeplustest.Rows([]any{1672531202000000, 1}, []any{1672531203000000, 2})},
+		KeepOpen: true,
+	})
+
+	reconnects := make(chan timeplus.ReconnectEvent, 1)
+	result, err := server.Client().QueryStreamResumable("select _tp_time, v from s", 10, 100, timeplus.ResumeOptions{
+		InitialBackoff: 10 * time.Millisecond,
+		OnReconnect: func(e timeplus.ReconnectEvent) {
+			reconnects <- e
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to query: %s", err)
+	}
+	defer result.Cancel()
+
+	values := make([]float64, 0)
+	for item := range result.ResultStream.Observe() {
+		if item.E != nil {
+			t.Fatalf("unexpected error %s", item.E)
+		}
+		for _, row := range *item.V.(*timeplus.DataEvent) {
+			values = append(values, row[1].(float64))
+		}
+		if len(values) >= 2 {
+			break
+		}
+	}
+	if fmt.Sprint(values) != "[1 2]" {
+		t.Errorf("expected the replayed row to be dropped, got %v", values)
+	}
+	if e := <-reconnects; e.SeekTo != "2023-01-01 00:00:02.000000000" {
+		t.Errorf("expected the microseconds to be resumed from, got %s", e.SeekTo)
+	}
+}
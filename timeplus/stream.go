@@ -0,0 +1,167 @@
+package timeplus
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/reactivex/rxgo/v2"
+
+	"github.com/timeplus-io/go-client/utils"
+)
+
+// queryConn is a single sse connection of a running query
+type queryConn struct {
+	res       *http.Response
+	reader    *bufio.Reader
+	metadata  QueryInfo
+	onEvent   func(QueryEvent)
+	closeOnce sync.Once
+}
+
+func (s *TimeplusClient) openQuery(ctx context.Context, query Query, onEvent func(QueryEvent)) (*queryConn, error) {
+	if err := query.Policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	createQueryUrl := fmt.Sprintf("%s/queries", s.baseUrl())
+	res, err := utils.SSEHttpRequestWithContext(ctx, http.MethodPost, createQueryUrl, query, s.streamClient, s.headers())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query : %w", err)
+	}
+
+	if res.StatusCode > 299 || res.StatusCode < 200 {
+		defer res.Body.Close()
+		resBody, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("failed to create query : request failed with status code %d, response body %s", res.StatusCode, resBody)
+	}
+
+	conn := &queryConn{
+		res:     res,
+		reader:  bufio.NewReader(res.Body),
+		onEvent: onEvent,
+	}
+
+	// Read the first result from sse which should be an event
+	line, err := conn.readLine()
+	if err != nil {
+		conn.close()
+		return nil, fmt.Errorf("failed to retrieve query metadata: %w", err)
+	}
+	field, eventName := parseField(line)
+	if field != "event" {
+		conn.close()
+		return nil, fmt.Errorf("the first result from sse has to be a event, got %s", line)
+	}
+
+	dataLine, err := conn.readLine()
+	if err != nil {
+		conn.close()
+		return nil, fmt.Errorf("failed to retrieve query metadata: %w", err)
+	}
+	_, eventContentData := parseField(dataLine)
+	if eventName != "query" {
+		conn.close()
+		return nil, fmt.Errorf("the first event from sse has to be a query, got %s", eventName)
+	}
+	if err := json.Unmarshal([]byte(eventContentData), &conn.metadata); err != nil {
+		conn.close()
+		return nil, fmt.Errorf("failed to unmarshall query header: %w", err)
+	}
+
+	return conn, nil
+}
+
+// next returns the next batch of data, io.EOF is returned when the server ends the query.
+// Other server events are passed to onEvent, an error event ends the query with QueryError.
+func (c *queryConn) next() (*DataEvent, error) {
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) == 0 {
+			continue
+		}
+
+		field, value := parseField(line)
+		if field == "event" {
+			dataLine, err := c.readLine()
+			if err != nil {
+				return nil, err
+			}
+			_, data := parseField(dataLine)
+
+			event := QueryEvent{
+				Type: value,
+				Data: json.RawMessage(data),
+			}
+			if c.onEvent != nil {
+				c.onEvent(event)
+			}
+			if event.Type == QueryEventError {
+				return nil, event.Error()
+			}
+			continue
+		}
+
+		var m DataEvent
+		if err := json.Unmarshal([]byte(value), &m); err != nil {
+			return nil, fmt.Errorf("invalide sse response, %s", line)
+		}
+		return &m, nil
+	}
+}
+
+func (c *queryConn) readLine() (string, error) {
+	return readCompleteLine(c.reader)
+}
+
+func (c *queryConn) close() {
+	c.closeOnce.Do(func() {
+		c.res.Body.Close()
+	})
+}
+
+func readCompleteLine(reader *bufio.Reader) (string, error) {
+	var line []byte
+	var isPrefix bool
+	var err error
+
+	isPrefix = true
+	for isPrefix {
+		var chunk []byte
+		chunk, isPrefix, err = reader.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		line = append(line, chunk...)
+	}
+
+	return string(line), nil
+}
+
+// parseField splits a sse line into its field name and value
+func parseField(line string) (string, string) {
+	colonIndex := strings.Index(line, ":")
+	if colonIndex < 0 {
+		return strings.TrimSpace(line), ""
+	}
+	return strings.TrimSpace(line[0:colonIndex]), strings.TrimSpace(line[colonIndex+1:])
+}
+
+// sendItem returns false if ctx is done before the item is consumed
+func sendItem(ctx context.Context, ch chan<- rxgo.Item, item rxgo.Item) bool {
+	select {
+	case ch <- item:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,228 @@
+package timeplustest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/timeplus-io/go-client/timeplus"
+)
+
+// ScriptEvent is a step of a scripted query, see Rows, Event, Metrics, Error, Delay and Disconnect
+type ScriptEvent struct {
+	Rows       [][]any
+	Event      string
+	Data       any
+	Delay      time.Duration
+	Disconnect bool
+}
+
+// Rows sends one batch of the query result
+func Rows(rows ...[]any) ScriptEvent {
+	return ScriptEvent{Rows: rows}
+}
+
+// Event sends a non data event of eventType
+func Event(eventType string, data any) ScriptEvent {
+	return ScriptEvent{Event: eventType, Data: data}
+}
+
+func Metrics(metrics timeplus.QueryMetrics) ScriptEvent {
+	return Event(timeplus.QueryEventMetrics, metrics)
+}
+
+// Error sends an error event, which ends the query on the client
+func Error(code int, message string) ScriptEvent {
+	return Event(timeplus.QueryEventError, timeplus.QueryError{Code: code, Message: message})
+}
+
+// Delay waits before the next event
+func Delay(d time.Duration) ScriptEvent {
+	return ScriptEvent{Delay: d}
+}
+
+// Disconnect drops the connection of the query without ending the response, like a network failure
+func Disconnect() ScriptEvent {
+	return ScriptEvent{Disconnect: true}
+}
+
+type QueryScript struct {
+	Header []timeplus.ColumnDef
+	Events []ScriptEvent
+	// KeepOpen keeps the query running after the events until the client cancels it, otherwise the
+	// server ends the query, which completes the result stream. See Disconnect for a dropped connection.
+	KeepOpen bool
+	// Times is how many queries are answered by the script, 0 means all of them
+	Times int
+}
+
+type queryScript struct {
+	match  string
+	script *QueryScript
+}
+
+// ScriptQuery answers the queries whose sql contains match with script, the earliest matching script
+// is used. Queries without script tail the rows of the stream they select from.
+func (s *Server) ScriptQuery(match string, script QueryScript) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.scripts = append(s.scripts, queryScript{match: match, script: &script})
+}
+
+// takeScript returns the script of sql, the caller has to hold the lock
+func (s *Server) takeScript(sql string) *QueryScript {
+	for index, qs := range s.scripts {
+		if !strings.Contains(sql, qs.match) {
+			continue
+		}
+		script := *qs.script
+		if qs.script.Times > 0 {
+			qs.script.Times--
+			if qs.script.Times == 0 {
+				s.scripts = append(s.scripts[:index], s.scripts[index+1:]...)
+			}
+		}
+		return &script
+	}
+	return nil
+}
+
+var fromPattern = regexp.MustCompile("(?i)\\bfrom\\s+`?([a-zA-Z_][a-zA-Z0-9_.]*)`?")
+
+func (s *Server) query(w http.ResponseWriter, r *http.Request, body []byte) {
+	var query timeplus.Query
+	if err := json.Unmarshal(body, &query); err != nil {
+		http.Error(w, "invalid query", http.StatusBadRequest)
+		return
+	}
+
+	s.lock.Lock()
+	s.queryCount++
+	info := timeplus.QueryInfo{
+		ID:        fmt.Sprintf("query-%d", s.queryCount),
+		SQL:       query.SQL,
+		Tags:      query.Tags,
+		StartTime: time.Now().UnixMilli(),
+		Status:    "running",
+	}
+	script := s.takeScript(query.SQL)
+
+	var tailed *stream
+	if script == nil {
+		if match := fromPattern.FindStringSubmatch(query.SQL); match != nil {
+			tailed = s.findStream(match[1])
+		}
+	}
+	s.lock.Unlock()
+
+	if script == nil && tailed == nil {
+		http.Error(w, fmt.Sprintf("no scripted result or stream for query %s", query.SQL), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+	sse := &sseWriter{w: w, flusher: flusher}
+
+	if script != nil {
+		info.Result.Header = script.Header
+		sse.event(timeplus.QueryEventQuery, info)
+		s.runScript(r, sse, script)
+		return
+	}
+
+	s.lock.Lock()
+	info.Result.Header = tailed.def.Columns
+	s.lock.Unlock()
+	sse.event(timeplus.QueryEventQuery, info)
+	s.tail(r, sse, tailed, query.Policy.Count)
+}
+
+func (s *Server) runScript(r *http.Request, sse *sseWriter, script *QueryScript) {
+	for _, event := range script.Events {
+		switch {
+		case event.Delay > 0:
+			select {
+			case <-time.After(event.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		case event.Disconnect:
+			sse.flush()
+			panic(http.ErrAbortHandler)
+		case len(event.Event) > 0:
+			sse.event(event.Event, event.Data)
+		default:
+			sse.data(event.Rows)
+		}
+	}
+
+	if script.KeepOpen {
+		<-r.Context().Done()
+	}
+}
+
+// tail sends the rows of st in batches of batchCount, including the rows ingested while the query runs
+func (s *Server) tail(r *http.Request, sse *sseWriter, st *stream, batchCount int) {
+	sent := 0
+	for {
+		s.lock.Lock()
+		rows := st.rows[sent:]
+		columns := st.def.Columns
+		updated := st.updated
+		s.lock.Unlock()
+
+		for len(rows) > 0 {
+			size := len(rows)
+			if batchCount > 0 && batchCount < size {
+				size = batchCount
+			}
+
+			batch := make([][]any, size)
+			for index, row := range rows[:size] {
+				values := make([]any, len(columns))
+				for i, col := range columns {
+					values[i] = row[col.Name]
+				}
+				batch[index] = values
+			}
+			sse.data(batch)
+
+			rows = rows[size:]
+			sent += size
+		}
+
+		select {
+		case <-updated:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func (s *sseWriter) event(eventType string, data any) {
+	payload, _ := json.Marshal(data)
+	fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", eventType, payload)
+	s.flush()
+}
+
+func (s *sseWriter) data(rows [][]any) {
+	payload, _ := json.Marshal(rows)
+	fmt.Fprintf(s.w, "data: %s\n\n", payload)
+	s.flush()
+}
+
+func (s *sseWriter) flush() {
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+}
@@ -0,0 +1,538 @@
+// Package timeplustest provides an in-process fake of the Timeplus REST API for unit tests.
+//
+// The Server keeps streams, views and ingested rows in memory and answers streaming queries over
+// sse, either from a scripted result or by tailing the rows of the queried stream. Faults can be
+// injected to test how the code under test handles failing requests.
+package timeplustest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/timeplus-io/go-client/timeplus"
+	"github.com/timeplus-io/go-client/utils"
+)
+
+// Row is an ingested row keyed by column name
+type Row map[string]any
+
+// Request is a request received by the server, recorded for assertions
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// Fault makes matching requests fail instead of being served
+type Fault struct {
+	// Method and Path select the requests, Path is matched as substring of the url path.
+	// Empty values match every request.
+	Method string
+	Path   string
+	// Status is the response status, 500 is used if zero
+	Status int
+	Body   string
+	// Delay is waited before responding, a fault with only a delay slows the request down but serves it
+	Delay time.Duration
+	// Times is how many requests fail, 0 means all of them
+	Times int
+}
+
+type Server struct {
+	URL string
+
+	server *httptest.Server
+
+	lock       sync.Mutex
+	apikey     string
+	streams    []*stream
+	views      []timeplus.View
+	statements []string
+	sqlScripts []sqlScript
+	scripts    []queryScript
+	faults     []*Fault
+	requests   []Request
+	queryCount int
+}
+
+type stream struct {
+	def  timeplus.StreamDef
+	rows []Row
+	// updated is closed and replaced whenever rows are ingested, so running queries see the new rows
+	updated chan struct{}
+}
+
+type sqlScript struct {
+	match  string
+	result timeplus.QueryResult
+}
+
+// NewServer starts a fake server, Close shuts it down
+func NewServer() *Server {
+	s := &Server{}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+	return s
+}
+
+// NewUnixServer starts a fake server listening on the unix domain socket at path, its URL is unix://path
+func NewUnixServer(path string) (*Server, error) {
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+
+	s := &Server{}
+	s.server = httptest.NewUnstartedServer(http.HandlerFunc(s.serveHTTP))
+	s.server.Listener = listener
+	s.server.Start()
+	s.URL = utils.UnixScheme + path
+	return s, nil
+}
+
+func (s *Server) Close() {
+	s.server.CloseClientConnections()
+	s.server.Close()
+}
+
+// Client returns a client of the server using the api key required by the server
+func (s *Server) Client() *timeplus.TimeplusClient {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return timeplus.NewCient(s.URL, "", s.apikey)
+}
+
+func (s *Server) LowLevelClient() *timeplus.TimeplusLowLevelClient {
+	return timeplus.NewLowLevelCient(s.URL)
+}
+
+// RequireAPIKey rejects the requests of the REST API without key, the low level ingest is not authenticated
+func (s *Server) RequireAPIKey(key string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.apikey = key
+}
+
+// AddStream creates a stream with rows, as if they were created and ingested by a client
+func (s *Server) AddStream(def timeplus.StreamDef, rows ...Row) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	st := s.findStream(def.Name)
+	if st == nil {
+		st = &stream{def: def, updated: make(chan struct{})}
+		s.streams = append(s.streams, st)
+	}
+	st.def = def
+	s.appendRows(st, rows)
+}
+
+// Stream returns the definition of the stream name
+func (s *Server) Stream(name string) (timeplus.StreamDef, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	st := s.findStream(name)
+	if st == nil {
+		return timeplus.StreamDef{}, false
+	}
+	return st.def, true
+}
+
+func (s *Server) Streams() []timeplus.StreamDef {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.streamDefs()
+}
+
+// Rows returns the rows ingested into the stream name
+func (s *Server) Rows(name string) []Row {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	st := s.findStream(name)
+	if st == nil {
+		return nil
+	}
+	rows := make([]Row, len(st.rows))
+	copy(rows, st.rows)
+	return rows
+}
+
+func (s *Server) Views() []timeplus.View {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	views := make([]timeplus.View, len(s.views))
+	copy(views, s.views)
+	return views
+}
+
+// Statements returns the sql executed through the sql endpoint
+func (s *Server) Statements() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	statements := make([]string, len(s.statements))
+	copy(statements, s.statements)
+	return statements
+}
+
+// Requests returns all requests received by the server
+func (s *Server) Requests() []Request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	requests := make([]Request, len(s.requests))
+	copy(requests, s.requests)
+	return requests
+}
+
+// ScriptSQL answers the sql containing match with result, the earliest matching script is used
+func (s *Server) ScriptSQL(match string, result timeplus.QueryResult) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.sqlScripts = append(s.sqlScripts, sqlScript{match: match, result: result})
+}
+
+func (s *Server) InjectFault(fault Fault) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	f := fault
+	s.faults = append(s.faults, &f)
+}
+
+func (s *Server) ClearFaults() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults = nil
+}
+
+func (s *Server) findStream(name string) *stream {
+	for _, st := range s.streams {
+		if st.def.Name == name {
+			return st
+		}
+	}
+	return nil
+}
+
+func (s *Server) streamDefs() []timeplus.StreamDef {
+	defs := make([]timeplus.StreamDef, len(s.streams))
+	for index, st := range s.streams {
+		defs[index] = st.def
+	}
+	return defs
+}
+
+// appendRows adds rows to st and wakes up the queries of st, the caller has to hold the lock
+func (s *Server) appendRows(st *stream, rows []Row) {
+	if len(rows) == 0 {
+		return
+	}
+	st.rows = append(st.rows, rows...)
+	close(st.updated)
+	st.updated = make(chan struct{})
+}
+
+// takeFault returns the fault of r, the caller has to hold the lock
+func (s *Server) takeFault(r *http.Request) *Fault {
+	for index, f := range s.faults {
+		if len(f.Method) > 0 && f.Method != r.Method {
+			continue
+		}
+		if len(f.Path) > 0 && !strings.Contains(r.URL.Path, f.Path) {
+			continue
+		}
+
+		fault := *f
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:index], s.faults[index+1:]...)
+			}
+		}
+		return &fault
+	}
+	return nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := readBody(r)
+
+	s.lock.Lock()
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Header: r.Header.Clone(),
+		Body:   body,
+	})
+	fault := s.takeFault(r)
+	apikey := s.apikey
+	s.lock.Unlock()
+
+	if fault != nil {
+		if fault.Delay > 0 {
+			select {
+			case <-time.After(fault.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fault.Status != 0 || len(fault.Body) > 0 || fault.Delay == 0 {
+			status := fault.Status
+			if status == 0 {
+				status = http.StatusInternalServerError
+			}
+			http.Error(w, fault.Body, status)
+			return
+		}
+	}
+
+	if path, ok := strings.CutPrefix(r.URL.Path, "/proton/v1/ingest/streams/"); ok {
+		s.ingest(w, path, body)
+		return
+	}
+
+	apiPrefix := "/api/" + timeplus.APIVersion
+	index := strings.Index(r.URL.Path, apiPrefix)
+	if index < 0 {
+		http.NotFound(w, r)
+		return
+	}
+	path := r.URL.Path[index+len(apiPrefix):]
+
+	if len(apikey) > 0 && r.Header.Get("X-Api-Key") != apikey {
+		http.Error(w, "invalid api key", http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case path == "/streams" && r.Method == http.MethodGet:
+		s.lock.Lock()
+		defs := s.streamDefs()
+		s.lock.Unlock()
+		writeJSON(w, defs)
+	case path == "/streams" && r.Method == http.MethodPost:
+		s.createStream(w, body)
+	case strings.HasPrefix(path, "/streams/") && strings.HasSuffix(path, "/ingest") && r.Method == http.MethodPost:
+		s.ingest(w, strings.TrimSuffix(strings.TrimPrefix(path, "/streams/"), "/ingest"), body)
+	case strings.HasPrefix(path, "/streams/") && r.Method == http.MethodDelete:
+		s.deleteStream(w, strings.TrimPrefix(path, "/streams/"))
+	case path == "/views" && r.Method == http.MethodGet:
+		writeJSON(w, s.Views())
+	case path == "/views" && r.Method == http.MethodPost:
+		s.createView(w, body)
+	case path == "/sql" && r.Method == http.MethodPost:
+		s.execSQL(w, body)
+	case path == "/queries" && r.Method == http.MethodPost:
+		s.query(w, r, body)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) createStream(w http.ResponseWriter, body []byte) {
+	var def timeplus.StreamDef
+	if err := json.Unmarshal(body, &def); err != nil || len(def.Name) == 0 {
+		http.Error(w, "invalid stream definition", http.StatusBadRequest)
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.findStream(def.Name) != nil {
+		http.Error(w, fmt.Sprintf("stream %s already exists", def.Name), http.StatusConflict)
+		return
+	}
+	s.streams = append(s.streams, &stream{def: def, updated: make(chan struct{})})
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) deleteStream(w http.ResponseWriter, name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for index, st := range s.streams {
+		if st.def.Name == name {
+			s.streams = append(s.streams[:index], s.streams[index+1:]...)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	http.Error(w, fmt.Sprintf("stream %s not found", name), http.StatusNotFound)
+}
+
+func (s *Server) ingest(w http.ResponseWriter, name string, body []byte) {
+	var data timeplus.IngestData
+	if err := json.Unmarshal(body, &data); err != nil {
+		http.Error(w, "invalid ingest data", http.StatusBadRequest)
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	st := s.findStream(name)
+	if st == nil {
+		http.Error(w, fmt.Sprintf("stream %s not found", name), http.StatusNotFound)
+		return
+	}
+
+	columns := make(map[string]bool, len(st.def.Columns))
+	for _, col := range st.def.Columns {
+		columns[col.Name] = true
+	}
+
+	rows := make([]Row, len(data.Data))
+	for index, values := range data.Data {
+		if len(values) != len(data.Columns) {
+			http.Error(w, fmt.Sprintf("row %d has %d values for %d columns", index, len(values), len(data.Columns)), http.StatusBadRequest)
+			return
+		}
+		row := make(Row, len(values))
+		for i, col := range data.Columns {
+			if !columns[col] {
+				http.Error(w, fmt.Sprintf("unknown column %s of stream %s", col, name), http.StatusBadRequest)
+				return
+			}
+			row[col] = values[i]
+		}
+		rows[index] = row
+	}
+	s.appendRows(st, rows)
+}
+
+func (s *Server) createView(w http.ResponseWriter, body []byte) {
+	var view timeplus.View
+	if err := json.Unmarshal(body, &view); err != nil || len(view.Name) == 0 {
+		http.Error(w, "invalid view", http.StatusBadRequest)
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, v := range s.views {
+		if v.Name == view.Name {
+			http.Error(w, fmt.Sprintf("view %s already exists", view.Name), http.StatusConflict)
+			return
+		}
+	}
+	s.views = append(s.views, view)
+	w.WriteHeader(http.StatusCreated)
+}
+
+var (
+	alterStreamPattern   = regexp.MustCompile("(?is)^\\s*ALTER\\s+STREAM\\s+`?([^`\\s]+)`?\\s+(.*)$")
+	addColumnPattern     = regexp.MustCompile("(?is)^ADD\\s+COLUMN\\s+`?([^`\\s]+)`?\\s+(.+)$")
+	modifyCommentPattern = regexp.MustCompile(`(?is)^MODIFY\s+COMMENT\s+'((?:[^'\\]|\\.)*)'\s*$`)
+)
+
+// execSQL applies the ADD COLUMN and MODIFY COMMENT alterations of streams, other sql is only recorded
+func (s *Server) execSQL(w http.ResponseWriter, body []byte) {
+	var req timeplus.SQLRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "invalid sql request", http.StatusBadRequest)
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.statements = append(s.statements, req.SQL)
+
+	if match := alterStreamPattern.FindStringSubmatch(req.SQL); match != nil {
+		st := s.findStream(match[1])
+		if st == nil {
+			http.Error(w, fmt.Sprintf("stream %s not found", match[1]), http.StatusNotFound)
+			return
+		}
+		if err := alterStream(st, match[2]); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	for _, script := range s.sqlScripts {
+		if strings.Contains(req.SQL, script.match) {
+			writeJSON(w, script.result)
+			return
+		}
+	}
+	writeJSON(w, timeplus.QueryResult{})
+}
+
+func alterStream(st *stream, clause string) error {
+	if match := modifyCommentPattern.FindStringSubmatch(clause); match != nil {
+		st.def.Description = unescape(match[1])
+		return nil
+	}
+
+	added := make([]timeplus.ColumnDef, 0)
+	for _, part := range strings.Split(clause, ",") {
+		match := addColumnPattern.FindStringSubmatch(strings.TrimSpace(part))
+		if match == nil {
+			// other alterations are accepted without changing the stream
+			return nil
+		}
+		for _, col := range st.def.Columns {
+			if col.Name == match[1] {
+				return fmt.Errorf("column %s already exists", match[1])
+			}
+		}
+		added = append(added, timeplus.ColumnDef{Name: match[1], Type: strings.TrimSpace(match[2])})
+	}
+	st.def.Columns = append(st.def.Columns, added...)
+	return nil
+}
+
+// unescape reverts timeplus.QuoteString
+func unescape(s string) string {
+	var b strings.Builder
+	escaped := false
+	for _, c := range s {
+		if !escaped && c == '\\' {
+			escaped = true
+			continue
+		}
+		if escaped {
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case '0':
+				c = 0
+			}
+		}
+		escaped = false
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	defer r.Body.Close()
+	return io.ReadAll(r.Body)
+}
@@ -0,0 +1,225 @@
+package timeplustest_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timeplus-io/go-client/timeplus"
+	"github.com/timeplus-io/go-client/timeplustest"
+)
+
+func TestStreamsAndIngest(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	server.RequireAPIKey("secret")
+	client := server.Client()
+
+	def := timeplus.StreamDef{
+		Name:    "cars",
+		Columns: []timeplus.ColumnDef{{Name: "id", Type: "string"}, {Name: "speed", Type: "float64"}},
+	}
+	if err := client.CreateStream(def); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CreateStream(def); err == nil {
+		t.Errorf("expected error creating an existing stream")
+	}
+	if !client.ExistStream("cars") || client.ExistStream("trucks") {
+		t.Errorf("unexpected streams %v", server.Streams())
+	}
+
+	err := client.InsertData(&timeplus.IngestPayload{
+		Stream: "cars",
+		Data:   timeplus.IngestData{Columns: []string{"id", "speed"}, Data: [][]any{{"a", 10}, {"b", 20}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = server.LowLevelClient().InsertData(&timeplus.IngestPayload{
+		Stream: "cars",
+		Data:   timeplus.IngestData{Columns: []string{"id"}, Data: [][]any{{"c"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.InsertData(&timeplus.IngestPayload{
+		Stream: "cars",
+		Data:   timeplus.IngestData{Columns: []string{"unknown"}, Data: [][]any{{1}}},
+	})
+	if err == nil {
+		t.Errorf("expected error ingesting an unknown column")
+	}
+
+	rows := server.Rows("cars")
+	if len(rows) != 3 || rows[0]["id"] != "a" || rows[1]["speed"] != float64(20) || rows[2]["speed"] != nil {
+		t.Errorf("unexpected rows %v", rows)
+	}
+
+	if _, err := timeplus.NewCient(server.URL, "", "wrong").ListStream(); err == nil {
+		t.Errorf("expected error with a wrong api key")
+	}
+
+	if err := client.CreateView(timeplus.View{Name: "fast", Query: "select * from cars where speed > 15"}); err != nil {
+		t.Fatal(err)
+	}
+	if !client.ExistView("fast") || len(server.Views()) != 1 {
+		t.Errorf("unexpected views %v", server.Views())
+	}
+
+	if _, err := client.ExecSQL("ALTER STREAM `cars` ADD COLUMN `color` string", 1000); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ExecSQL("ALTER STREAM `cars` MODIFY COMMENT "+timeplus.QuoteString("it's fast"), 1000); err != nil {
+		t.Fatal(err)
+	}
+	stream, _ := server.Stream("cars")
+	if len(stream.Columns) != 3 || stream.Columns[2].Name != "color" || stream.Description != "it's fast" {
+		t.Errorf("unexpected altered stream %+v", stream)
+	}
+	comment := "line\nnext\ttab\rreturn \\ 'quoted'"
+	if _, err := client.ExecSQL("ALTER STREAM `cars` MODIFY COMMENT "+timeplus.QuoteString(comment), 1000); err != nil {
+		t.Fatal(err)
+	}
+	if stream, _ := server.Stream("cars"); stream.Description != comment {
+		t.Errorf("expected the escaped comment to round trip, got %q", stream.Description)
+	}
+
+	server.ScriptSQL("count()", timeplus.QueryResult{Data: [][]any{{float64(3)}}})
+	result, err := client.ExecSQL("select count() from table(cars)", 1000)
+	if err != nil || len(result.Data) != 1 || result.Data[0][0] != float64(3) {
+		t.Errorf("unexpected sql result %v %v", result, err)
+	}
+	if len(server.Statements()) != 4 {
+		t.Errorf("unexpected statements %v", server.Statements())
+	}
+
+	if err := client.DeleteStream("cars"); err != nil {
+		t.Fatal(err)
+	}
+	if client.ExistStream("cars") {
+		t.Errorf("stream not deleted")
+	}
+}
+
+func TestScriptedQuery(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	server.ScriptQuery("from cars", timeplustest.QueryScript{
+		Header: []timeplus.ColumnDef{{Name: "id", Type: "string"}},
+		Events: []timeplustest.ScriptEvent{
+			timeplustest.Rows([]any{"a"}, []any{"b"}),
+			timeplustest.Metrics(timeplus.QueryMetrics{Count: 2}),
+			timeplustest.Rows([]any{"c"}),
+			timeplustest.Error(42, "boom"),
+		},
+	})
+
+	events := make([]timeplus.QueryEvent, 0)
+	result, err := client.QueryStreamWithOptions("select id from cars", timeplus.QueryOptions{
+		OnEvent: func(e timeplus.QueryEvent) { events = append(events, e) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer result.Cancel()
+
+	if result.Metadata.ID != "query-1" || result.Metadata.Result.Header[0].Name != "id" {
+		t.Errorf("unexpected metadata %+v", result.Metadata)
+	}
+
+	rows := 0
+	var queryErr error
+	for item := range result.ResultStream.Observe() {
+		if item.E != nil {
+			queryErr = item.E
+			break
+		}
+		rows += len(*item.V.(*timeplus.DataEvent))
+	}
+	if rows != 3 || queryErr == nil || !strings.Contains(queryErr.Error(), "boom") {
+		t.Errorf("unexpected result, %d rows, error %v", rows, queryErr)
+	}
+	if len(events) != 2 || events[0].Type != timeplus.QueryEventMetrics {
+		t.Errorf("unexpected events %v", events)
+	}
+
+	if _, err := client.QueryStream("select 1", 10, 100); err == nil {
+		t.Errorf("expected error for a query without script or stream")
+	}
+}
+
+func TestTailQuery(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	server.AddStream(timeplus.StreamDef{
+		Name:    "cars",
+		Columns: []timeplus.ColumnDef{{Name: "id", Type: "string"}, {Name: "speed", Type: "float64"}},
+	}, timeplustest.Row{"id": "a", "speed": 1})
+
+	result, err := client.QueryStream("select * from cars", 10, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer result.Cancel()
+
+	items := result.ResultStream.Observe()
+	first := <-items
+	if rows := *first.V.(*timeplus.DataEvent); len(rows) != 1 || rows[0][0] != "a" {
+		t.Errorf("unexpected first batch %v", rows)
+	}
+
+	client.InsertData(&timeplus.IngestPayload{
+		Stream: "cars",
+		Data:   timeplus.IngestData{Columns: []string{"speed", "id"}, Data: [][]any{{2, "b"}}},
+	})
+	select {
+	case item := <-items:
+		if rows := *item.V.(*timeplus.DataEvent); len(rows) != 1 || rows[0][0] != "b" || rows[0][1] != float64(2) {
+			t.Errorf("unexpected tailed batch %v", rows)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("ingested row not delivered to the running query")
+	}
+}
+
+func TestFaults(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	client := server.Client()
+
+	server.InjectFault(timeplustest.Fault{Method: http.MethodPost, Path: "/streams", Status: http.StatusServiceUnavailable, Times: 1})
+	def := timeplus.StreamDef{Name: "cars", Columns: []timeplus.ColumnDef{{Name: "id", Type: "string"}}}
+	if err := client.CreateStream(def); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("expected injected fault, got %v", err)
+	}
+	if err := client.CreateStream(def); err != nil {
+		t.Errorf("fault should only apply once, got %v", err)
+	}
+
+	server.InjectFault(timeplustest.Fault{Path: "/ingest", Delay: 50 * time.Millisecond})
+	start := time.Now()
+	err := client.InsertData(&timeplus.IngestPayload{
+		Stream: "cars",
+		Data:   timeplus.IngestData{Columns: []string{"id"}, Data: [][]any{{"a"}}},
+	})
+	if err != nil || time.Since(start) < 50*time.Millisecond || len(server.Rows("cars")) != 1 {
+		t.Errorf("expected delayed but served ingest, got %v", err)
+	}
+
+	server.ClearFaults()
+	server.InjectFault(timeplustest.Fault{Path: "/queries"})
+	if _, err := client.QueryStream("select * from cars", 10, 100); err == nil {
+		t.Errorf("expected injected query fault")
+	}
+
+	requests := server.Requests()
+	if len(requests) != 4 || !strings.HasSuffix(requests[3].Path, "/queries") {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
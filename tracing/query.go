@@ -0,0 +1,117 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/timeplus-io/go-client/timeplus"
+)
+
+// queryBody follows the sse of a streaming query, the query id, the batches and the error
+// event are recorded on span which ends when the body is drained or closed
+type queryBody struct {
+	body io.ReadCloser
+	span trace.Span
+
+	// lock guards the state below, Close is called by the goroutine cancelling the query
+	lock    sync.Mutex
+	line    []byte
+	event   string
+	batches int
+	rows    int
+	endOnce sync.Once
+}
+
+func newQueryBody(body io.ReadCloser, span trace.Span) *queryBody {
+	return &queryBody{body: body, span: span}
+}
+
+func (b *queryBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.lock.Lock()
+	b.scan(p[:n])
+	b.lock.Unlock()
+	if err == io.EOF {
+		b.end()
+	} else if err != nil {
+		b.span.RecordError(err)
+		b.end()
+	}
+	return n, err
+}
+
+func (b *queryBody) Close() error {
+	b.end()
+	return b.body.Close()
+}
+
+func (b *queryBody) end() {
+	b.endOnce.Do(func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		b.span.SetAttributes(QueryBatchesKey.Int(b.batches), RowsKey.Int(b.rows))
+		b.span.End()
+	})
+}
+
+func (b *queryBody) scan(data []byte) {
+	for len(data) > 0 {
+		index := bytes.IndexByte(data, '\n')
+		if index < 0 {
+			b.line = append(b.line, data...)
+			return
+		}
+		b.line = append(b.line, data[:index]...)
+		b.handleLine(strings.TrimSuffix(string(b.line), "\r"))
+		b.line = b.line[:0]
+		data = data[index+1:]
+	}
+}
+
+func (b *queryBody) handleLine(line string) {
+	if len(line) == 0 {
+		return
+	}
+
+	field, value, _ := strings.Cut(line, ":")
+	value = strings.TrimSpace(value)
+	if field == "event" {
+		b.event = value
+		return
+	}
+	if field != "data" {
+		return
+	}
+
+	event := b.event
+	b.event = ""
+	switch event {
+	case "":
+		var rows []json.RawMessage
+		json.Unmarshal([]byte(value), &rows)
+		b.batches++
+		b.rows += len(rows)
+		b.span.AddEvent("batch", trace.WithAttributes(
+			RowsKey.Int(len(rows)),
+			attribute.Int("timeplus.batch.bytes", len(value)),
+		))
+	case timeplus.QueryEventQuery:
+		var info timeplus.QueryInfo
+		if err := json.Unmarshal([]byte(value), &info); err == nil && len(info.ID) > 0 {
+			b.span.SetAttributes(QueryIDKey.String(info.ID))
+		}
+	case timeplus.QueryEventError:
+		queryErr := timeplus.QueryEvent{Type: event, Data: json.RawMessage(value)}.Error()
+		b.span.RecordError(queryErr)
+		b.span.SetStatus(codes.Error, queryErr.Error())
+	default:
+		b.span.AddEvent(event)
+	}
+}
@@ -0,0 +1,212 @@
+// Package tracing traces the calls of the Timeplus clients with OpenTelemetry.
+//
+// Interceptor is added to utils.HTTPClientConfig.Interceptors, it starts a client span for every
+// request, named after the client operation, and propagates the trace context to the server.
+// The span of a streaming query lasts until the query ends and records an event for every batch.
+//
+// The spans are children of the span in the context of the request, send the calls with the context
+// of the caller to link them to its trace:
+//
+//	client.WithContext(ctx).InsertData(payload)
+//
+// The calls of a client without context start a new trace.
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/timeplus-io/go-client/timeplus"
+	"github.com/timeplus-io/go-client/utils"
+)
+
+const instrumentationName = "github.com/timeplus-io/go-client/tracing"
+
+// The attributes of the spans besides the http semantic conventions
+const (
+	StreamKey       = attribute.Key("timeplus.stream")
+	RowsKey         = attribute.Key("timeplus.rows")
+	QueryIDKey      = attribute.Key("timeplus.query.id")
+	QueryBatchesKey = attribute.Key("timeplus.query.batches")
+)
+
+type Config struct {
+	// TracerProvider defaults to the global tracer provider
+	TracerProvider trace.TracerProvider
+	// Propagator defaults to the W3C trace context
+	Propagator propagation.TextMapPropagator
+}
+
+// operation is the client call of a request
+type operation struct {
+	name   string
+	stream string
+}
+
+func Interceptor(config Config) utils.Interceptor {
+	if config.TracerProvider == nil {
+		config.TracerProvider = otel.GetTracerProvider()
+	}
+	if config.Propagator == nil {
+		config.Propagator = propagation.TraceContext{}
+	}
+	tracer := config.TracerProvider.Tracer(instrumentationName)
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return utils.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			op := parseOperation(req)
+
+			attrs := []attribute.KeyValue{
+				semconv.DBSystemKey.String("timeplus"),
+				semconv.HTTPRequestMethodKey.String(req.Method),
+				semconv.ServerAddress(req.URL.Hostname()),
+				semconv.URLFull(req.URL.Redacted()),
+			}
+			if req.ContentLength > 0 {
+				attrs = append(attrs, semconv.HTTPRequestBodySize(int(req.ContentLength)))
+			}
+			if len(op.stream) > 0 {
+				attrs = append(attrs, StreamKey.String(op.stream))
+			}
+			if op.name == "ingest" {
+				if rows, ok := countRows(req); ok {
+					attrs = append(attrs, RowsKey.Int(rows))
+				}
+			}
+
+			ctx, span := tracer.Start(req.Context(), "timeplus."+op.name,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(attrs...),
+			)
+
+			req = req.Clone(ctx)
+			config.Propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+			res, err := next.RoundTrip(req)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				span.End()
+				return res, err
+			}
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(res.StatusCode))
+			if res.StatusCode >= 400 {
+				span.SetStatus(codes.Error, fmt.Sprintf("status code %d", res.StatusCode))
+			}
+
+			// the span of a running query ends with its response body
+			if op.name == "query" && res.StatusCode < 300 {
+				res.Body = newQueryBody(res.Body, span)
+				return res, nil
+			}
+			span.End()
+			return res, nil
+		})
+	}
+}
+
+// requestBody returns a copy of the body of req without consuming it, which is only possible
+// if the body can be read again. The clients set GetBody on all their requests.
+func requestBody(req *http.Request) (io.ReadCloser, bool) {
+	if req.GetBody == nil || req.Body == nil || req.Body == http.NoBody {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	return body, true
+}
+
+func parseOperation(req *http.Request) operation {
+	method := req.Method
+	path := req.URL.Path
+
+	if stream, ok := strings.CutPrefix(path, "/proton/v1/ingest/streams/"); ok {
+		return operation{name: "ingest", stream: stream}
+	}
+
+	index := strings.Index(path, "/api/"+timeplus.APIVersion)
+	if index < 0 {
+		return operation{name: "request"}
+	}
+	path = path[index+len("/api/"+timeplus.APIVersion):]
+
+	switch {
+	case path == "/streams" && method == http.MethodGet:
+		return operation{name: "list_streams"}
+	case path == "/streams" && method == http.MethodPost:
+		var def timeplus.StreamDef
+		if body, ok := requestBody(req); ok {
+			json.NewDecoder(body).Decode(&def)
+			body.Close()
+		}
+		return operation{name: "create_stream", stream: def.Name}
+	case strings.HasPrefix(path, "/streams/") && strings.HasSuffix(path, "/ingest"):
+		return operation{name: "ingest", stream: strings.TrimSuffix(strings.TrimPrefix(path, "/streams/"), "/ingest")}
+	case strings.HasPrefix(path, "/streams/") && method == http.MethodDelete:
+		return operation{name: "delete_stream", stream: strings.TrimPrefix(path, "/streams/")}
+	case path == "/views" && method == http.MethodGet:
+		return operation{name: "list_views"}
+	case path == "/views" && method == http.MethodPost:
+		return operation{name: "create_view"}
+	case path == "/sql":
+		return operation{name: "exec_sql"}
+	case path == "/queries" && method == http.MethodPost:
+		return operation{name: "query"}
+	}
+	return operation{name: "request"}
+}
+
+// countRows counts the rows of an ingest request, decoding one row at a time so a large ingest is not
+// held in memory a second time
+func countRows(req *http.Request) (int, bool) {
+	body, ok := requestBody(req)
+	if !ok {
+		return 0, false
+	}
+	defer body.Close()
+
+	decoder := json.NewDecoder(body)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return 0, false
+	}
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return 0, false
+		}
+		if key != "data" {
+			var skipped json.RawMessage
+			if err := decoder.Decode(&skipped); err != nil {
+				return 0, false
+			}
+			continue
+		}
+
+		if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+			return 0, false
+		}
+		rows := 0
+		var row json.RawMessage
+		for decoder.More() {
+			if err := decoder.Decode(&row); err != nil {
+				return 0, false
+			}
+			rows++
+		}
+		return rows, true
+	}
+	return 0, false
+}
@@ -0,0 +1,145 @@
+package tracing_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/timeplus-io/go-client/timeplus"
+	"github.com/timeplus-io/go-client/timeplustest"
+	"github.com/timeplus-io/go-client/tracing"
+	"github.com/timeplus-io/go-client/utils"
+)
+
+func spanAttr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	server.ScriptQuery("from cars", timeplustest.QueryScript{
+		Header: []timeplus.ColumnDef{{Name: "id", Type: "string"}},
+		Events: []timeplustest.ScriptEvent{
+			timeplustest.Rows([]any{"a"}, []any{"b"}),
+			timeplustest.Rows([]any{"c"}),
+			timeplustest.Error(1, "boom"),
+		},
+	})
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	config := utils.NewDefaultHTTPClientConfig()
+	config.Interceptors = []utils.Interceptor{tracing.Interceptor(tracing.Config{TracerProvider: provider})}
+	client := timeplus.NewCientWithHttpConfig(server.URL, "", "key", config)
+
+	if err := client.CreateStream(timeplus.StreamDef{Name: "cars", Columns: []timeplus.ColumnDef{{Name: "id", Type: "string"}}}); err != nil {
+		t.Fatal(err)
+	}
+	err := client.InsertData(&timeplus.IngestPayload{
+		Stream: "cars",
+		Data:   timeplus.IngestData{Columns: []string{"id"}, Data: [][]any{{"a"}, {"b"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.InsertData(&timeplus.IngestPayload{Stream: "trucks", Data: timeplus.IngestData{Columns: []string{"id"}}})
+
+	result, err := client.QueryStream("select id from cars", 10, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for item := range result.ResultStream.Observe() {
+		if item.E != nil {
+			break
+		}
+	}
+	result.Cancel()
+
+	deadline := time.Now().Add(time.Second)
+	for len(recorder.Ended()) < 4 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("expected 4 spans, got %d", len(spans))
+	}
+
+	create, ingest, failed, query := spans[0], spans[1], spans[2], spans[3]
+	if create.Name() != "timeplus.create_stream" || spanAttr(create, tracing.StreamKey).AsString() != "cars" {
+		t.Errorf("unexpected create span %s %v", create.Name(), create.Attributes())
+	}
+	if ingest.Name() != "timeplus.ingest" || spanAttr(ingest, tracing.RowsKey).AsInt64() != 2 ||
+		spanAttr(ingest, "http.response.status_code").AsInt64() != 200 || spanAttr(ingest, "http.request.body.size").AsInt64() == 0 {
+		t.Errorf("unexpected ingest span %v", ingest.Attributes())
+	}
+	if failed.Status().Code != codes.Error || spanAttr(failed, "http.response.status_code").AsInt64() != 404 {
+		t.Errorf("expected failed ingest span, got %v %v", failed.Status(), failed.Attributes())
+	}
+
+	if query.Name() != "timeplus.query" || spanAttr(query, tracing.QueryIDKey).AsString() != "query-1" ||
+		spanAttr(query, tracing.QueryBatchesKey).AsInt64() != 2 || spanAttr(query, tracing.RowsKey).AsInt64() != 3 {
+		t.Errorf("unexpected query span %v", query.Attributes())
+	}
+	if len(query.Events()) < 2 || query.Events()[0].Name != "batch" || query.Status().Code != codes.Error {
+		t.Errorf("unexpected query events %v status %v", query.Events(), query.Status())
+	}
+
+	// every request carries the trace context of its span
+	for index, req := range server.Requests() {
+		traceparent := req.Header.Get("Traceparent")
+		if !strings.Contains(traceparent, spans[index].SpanContext().SpanID().String()) {
+			t.Errorf("request %s %s has traceparent %q", req.Method, req.Path, traceparent)
+		}
+	}
+}
+
+func TestTracingParentContext(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	server.AddStream(timeplus.StreamDef{Name: "cars", Columns: []timeplus.ColumnDef{{Name: "id", Type: "string"}}})
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	config := utils.NewDefaultHTTPClientConfig()
+	config.Interceptors = []utils.Interceptor{tracing.Interceptor(tracing.Config{TracerProvider: provider})}
+	client := timeplus.NewLowLevelCientWithHttpConfig(server.URL, config)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+	err := client.WithContext(ctx).InsertData(&timeplus.IngestPayload{
+		Stream: "cars",
+		Data:   timeplus.IngestData{Columns: []string{"id"}, Data: [][]any{{"a"}, {"b"}, {"c"}}},
+	})
+	parent.End()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	ingest := spans[0]
+	if ingest.Parent().SpanID() != parent.SpanContext().SpanID() || ingest.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+		t.Errorf("ingest span not linked to the caller, parent %v", ingest.Parent())
+	}
+	if spanAttr(ingest, tracing.RowsKey).AsInt64() != 3 {
+		t.Errorf("unexpected ingest span %v", ingest.Attributes())
+	}
+	if rows := server.Rows("cars"); len(rows) != 3 {
+		t.Errorf("expected the body to reach the server, got %v", rows)
+	}
+}
@@ -0,0 +1,242 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authenticator adds the credentials to a request, it is called for every request so the
+// credentials can change while the client is running
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// AuthenticatorFunc turns a function into an Authenticator
+type AuthenticatorFunc func(req *http.Request) error
+
+func (f AuthenticatorFunc) Authenticate(req *http.Request) error {
+	return f(req)
+}
+
+// APIKeyAuth authenticates with the X-Api-Key header of Timeplus Cloud
+func APIKeyAuth(key string) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		req.Header.Set("X-Api-Key", key)
+		return nil
+	})
+}
+
+func BearerAuth(token string) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		return nil
+	})
+}
+
+// BasicAuth authenticates with username and password, such as the users of a self hosted Proton
+func BasicAuth(username string, password string) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		req.SetBasicAuth(username, password)
+		return nil
+	})
+}
+
+// APIKeyProvider authenticates with the api key returned by provider, which is called for every
+// request so the key can be rotated without recreating the client
+func APIKeyProvider(provider func(ctx context.Context) (string, error)) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		key, err := provider(req.Context())
+		if err != nil {
+			return fmt.Errorf("failed to get api key: %w", err)
+		}
+		req.Header.Set("X-Api-Key", key)
+		return nil
+	})
+}
+
+// BearerProvider authenticates with the bearer token returned by provider for every request
+func BearerProvider(provider func(ctx context.Context) (string, error)) Authenticator {
+	return AuthenticatorFunc(func(req *http.Request) error {
+		token, err := provider(req.Context())
+		if err != nil {
+			return fmt.Errorf("failed to get bearer token: %w", err)
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		return nil
+	})
+}
+
+// Invalidator is implemented by the authenticators caching credentials which can be refreshed,
+// such as OAuth2ClientCredentials
+type Invalidator interface {
+	Invalidate()
+}
+
+// AuthInterceptor authenticates every request with auth. If auth is an Invalidator, a request rejected
+// with 401 invalidates the credentials and is sent once more with refreshed ones.
+func AuthInterceptor(auth Authenticator) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			res, err := authenticatedRoundTrip(next, auth, req, req.Body)
+			if err != nil || res.StatusCode != http.StatusUnauthorized {
+				return res, err
+			}
+
+			invalidator, ok := auth.(Invalidator)
+			if !ok || (req.Body != nil && req.GetBody == nil) {
+				return res, nil
+			}
+			var body io.ReadCloser
+			if req.Body != nil {
+				if body, err = req.GetBody(); err != nil {
+					return res, nil
+				}
+			}
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+
+			invalidator.Invalidate()
+			return authenticatedRoundTrip(next, auth, req, body)
+		})
+	}
+}
+
+func authenticatedRoundTrip(next http.RoundTripper, auth Authenticator, req *http.Request, body io.ReadCloser) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Body = body
+	if err := auth.Authenticate(req); err != nil {
+		if body != nil {
+			body.Close()
+		}
+		return nil, err
+	}
+	return next.RoundTrip(req)
+}
+
+// DefaultTokenRefreshLeeway is how long before its expiry an OAuth2 token is refreshed
+const DefaultTokenRefreshLeeway = 30 * time.Second
+
+// OAuth2ClientCredentials gets bearer tokens with the OAuth2 client credentials grant,
+// the token is cached and refreshed shortly before it expires
+type OAuth2ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// EndpointParams are added to the token request, such as the audience
+	EndpointParams url.Values
+	// HTTPClient sends the token requests, a client verifying the tls of TokenURL is used if nil
+	HTTPClient *http.Client
+	// RefreshLeeway defaults to DefaultTokenRefreshLeeway
+	RefreshLeeway time.Duration
+
+	lock   sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+type oauth2Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func (o *OAuth2ClientCredentials) Authenticate(req *http.Request) error {
+	token, err := o.Token(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	return nil
+}
+
+// Token returns the cached token or requests a new one, concurrent callers wait for the same refresh
+func (o *OAuth2ClientCredentials) Token(ctx context.Context) (string, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	leeway := o.RefreshLeeway
+	if leeway <= 0 {
+		leeway = DefaultTokenRefreshLeeway
+	}
+	now := time.Now()
+	if len(o.token) > 0 && (o.expiry.IsZero() || now.Add(leeway).Before(o.expiry)) {
+		return o.token, nil
+	}
+
+	token, err := o.requestToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	o.token = token.AccessToken
+	o.expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		o.expiry = now.Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return o.token, nil
+}
+
+// Invalidate drops the cached token, such as after the server rejected it
+func (o *OAuth2ClientCredentials) Invalidate() {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.token = ""
+}
+
+func (o *OAuth2ClientCredentials) requestToken(ctx context.Context) (*oauth2Token, error) {
+	form := url.Values{}
+	for key, values := range o.EndpointParams {
+		form[key] = values
+	}
+	form.Set("grant_type", "client_credentials")
+	if len(o.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(o.ClientID), url.QueryEscape(o.ClientSecret))
+
+	// the default client of the package skips the tls verification, which must not be used to send the secret
+	client := o.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request oauth2 token: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read oauth2 token: %w", err)
+	}
+	if res.StatusCode > 299 || res.StatusCode < 200 {
+		return nil, fmt.Errorf("failed to request oauth2 token: status code %d, response body %s", res.StatusCode, body)
+	}
+
+	var token oauth2Token
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("failed to unmarshall oauth2 token: %w", err)
+	}
+	if len(token.AccessToken) == 0 {
+		return nil, fmt.Errorf("oauth2 token response without access_token")
+	}
+	if len(token.TokenType) > 0 && !strings.EqualFold(token.TokenType, "bearer") {
+		return nil, fmt.Errorf("unsupported oauth2 token type %s", token.TokenType)
+	}
+	return &token, nil
+}
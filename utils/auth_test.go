@@ -0,0 +1,168 @@
+package utils_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/timeplus-io/go-client/timeplus"
+	"github.com/timeplus-io/go-client/timeplustest"
+	"github.com/timeplus-io/go-client/utils"
+)
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	var lock sync.Mutex
+	issued := 0
+	expiresIn := 3600
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		r.ParseForm()
+		if user != "id" || password != "secret" || r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("scope") != "read write" {
+			http.Error(w, "invalid client", http.StatusUnauthorized)
+			return
+		}
+
+		lock.Lock()
+		issued++
+		token := fmt.Sprintf("token-%d", issued)
+		lock.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"access_token": token, "token_type": "Bearer", "expires_in": expiresIn})
+	}))
+	defer tokenServer.Close()
+
+	server := timeplustest.NewServer()
+	defer server.Close()
+
+	oauth := &utils.OAuth2ClientCredentials{
+		TokenURL:     tokenServer.URL,
+		ClientID:     "id",
+		ClientSecret: "secret",
+		Scopes:       []string{"read", "write"},
+	}
+	client := timeplus.NewCientWithAuthenticator(server.URL, "", oauth)
+
+	client.ListStream()
+	client.ListStream()
+	requests := server.Requests()
+	if issued != 1 || requests[1].Header.Get("Authorization") != "Bearer token-1" || requests[1].Header.Get("X-Api-Key") != "" {
+		t.Errorf("expected the cached token, issued %d, headers %v", issued, requests[1].Header)
+	}
+
+	// a token expiring within the refresh leeway is refreshed on the next request
+	oauth.Invalidate()
+	expiresIn = 10
+	client.ListStream()
+	client.ListStream()
+	requests = server.Requests()
+	if issued != 3 || requests[3].Header.Get("Authorization") != "Bearer token-3" {
+		t.Errorf("expected refreshed tokens, issued %d, headers %v", issued, requests[3].Header)
+	}
+
+	oauth.ClientSecret = "wrong"
+	oauth.Invalidate()
+	if _, err := client.ListStream(); err == nil {
+		t.Errorf("expected error when the token cannot be issued")
+	}
+	if len(server.Requests()) != 4 {
+		t.Errorf("unauthenticated request reached the server")
+	}
+}
+
+func TestOAuth2RevokedToken(t *testing.T) {
+	var lock sync.Mutex
+	issued := 0
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		issued++
+		token := fmt.Sprintf("token-%d", issued)
+		lock.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"access_token": token, "token_type": "Bearer", "expires_in": 3600})
+	}))
+	defer tokenServer.Close()
+
+	server := timeplustest.NewServer()
+	defer server.Close()
+	oauth := &utils.OAuth2ClientCredentials{TokenURL: tokenServer.URL, ClientID: "id", ClientSecret: "secret"}
+	client := timeplus.NewCientWithAuthenticator(server.URL, "", oauth)
+
+	client.ListStream()
+	server.InjectFault(timeplustest.Fault{Path: "/streams", Status: http.StatusUnauthorized, Times: 1})
+	if err := client.CreateStream(timeplus.StreamDef{Name: "cars", Columns: []timeplus.ColumnDef{{Name: "id", Type: "string"}}}); err != nil {
+		t.Fatalf("request not retried with a refreshed token: %s", err)
+	}
+
+	requests := server.Requests()
+	if issued != 2 || len(requests) != 3 || requests[2].Header.Get("Authorization") != "Bearer token-2" || len(requests[2].Body) == 0 {
+		t.Errorf("expected one retry with a refreshed token, issued %d, requests %v", issued, requests)
+	}
+	if _, ok := server.Stream("cars"); !ok {
+		t.Errorf("stream not created by the retried request")
+	}
+}
+
+func TestRotatedAPIKey(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	server.RequireAPIKey("key-1")
+
+	var lock sync.Mutex
+	key := "key-1"
+	client := timeplus.NewCientWithAuthenticator(server.URL, "", utils.APIKeyProvider(func(ctx context.Context) (string, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if len(key) == 0 {
+			return "", errors.New("no key")
+		}
+		return key, nil
+	}))
+
+	if _, err := client.ListStream(); err != nil {
+		t.Fatal(err)
+	}
+	server.ScriptQuery("select", timeplustest.QueryScript{})
+	result, err := client.QueryStream("select 1", 10, 100)
+	if err != nil {
+		t.Fatalf("streaming query not authenticated: %s", err)
+	}
+	result.Cancel()
+
+	server.RequireAPIKey("key-2")
+	lock.Lock()
+	key = "key-2"
+	lock.Unlock()
+	if _, err := client.ListStream(); err != nil {
+		t.Errorf("rotated key not used: %s", err)
+	}
+
+	lock.Lock()
+	key = ""
+	lock.Unlock()
+	if _, err := client.ListStream(); err == nil {
+		t.Errorf("expected error from the key provider")
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	server.AddStream(timeplus.StreamDef{Name: "s", Columns: []timeplus.ColumnDef{{Name: "v", Type: "int"}}})
+
+	config := utils.NewDefaultHTTPClientConfig()
+	config.Authenticator = utils.BasicAuth("default", "pass")
+	client := timeplus.NewLowLevelCientWithHttpConfig(server.URL, config)
+	err := client.InsertData(&timeplus.IngestPayload{Stream: "s", Data: timeplus.IngestData{Columns: []string{"v"}, Data: [][]any{{1}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	req.Header = server.Requests()[0].Header
+	if user, password, ok := req.BasicAuth(); !ok || user != "default" || password != "pass" {
+		t.Errorf("unexpected basic auth %v", req.Header)
+	}
+}
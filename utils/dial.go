@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// UnixScheme prefixes the address of a server listening on a unix domain socket, such as unix:///var/run/proton.sock
+const UnixScheme = "unix://"
+
+// unixBaseURL is the base url of the requests sent over a unix domain socket, its host is not dialed
+const unixBaseURL = "http://unix"
+
+// DialContextFunc dials the connections of a client, see net.Dialer.DialContext
+type DialContextFunc func(ctx context.Context, network string, address string) (net.Conn, error)
+
+// ResolveAddress returns the base url of address and the config to reach it. A unix:// address
+// is sent to http://unix and dialed at the socket path, without any proxy.
+func ResolveAddress(address string, config HTTPClientConfig) (string, HTTPClientConfig) {
+	socket, ok := strings.CutPrefix(address, UnixScheme)
+	if !ok {
+		return address, config
+	}
+
+	config.DialContext = func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "unix", socket)
+	}
+	config.ProxyURL = ""
+	config.DisableEnvironmentProxy = true
+	return unixBaseURL, config
+}
+
+// Validate loads the certificates and parses the proxy of config. The clients created with an invalid config
+// fail every request with the same error, use the constructors checking the config to fail when creating them.
+func (config HTTPClientConfig) Validate() error {
+	return config.applyTransport(http.DefaultTransport.(*http.Transport).Clone())
+}
+
+// configureTransport sets the tls, the proxy and the dialer of config to t,
+// a config which cannot be loaded fails every request
+func (config HTTPClientConfig) configureTransport(t *http.Transport) http.RoundTripper {
+	if err := config.applyTransport(t); err != nil {
+		err = fmt.Errorf("invalid http client config: %w", err)
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, err
+		})
+	}
+	return t
+}
+
+func (config HTTPClientConfig) applyTransport(t *http.Transport) error {
+	tlsConfig, err := config.TLS.build(config.InsecureSkipVerify)
+	if err != nil {
+		return err
+	}
+	t.TLSClientConfig = tlsConfig
+
+	switch {
+	case len(config.ProxyURL) > 0:
+		proxy, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid proxy url: %w", err)
+		}
+		if len(proxy.Scheme) == 0 || len(proxy.Host) == 0 {
+			return fmt.Errorf("invalid proxy url %s, should be like http://host:port", config.ProxyURL)
+		}
+		t.Proxy = http.ProxyURL(proxy)
+	case config.DisableEnvironmentProxy:
+		t.Proxy = nil
+	}
+
+	if config.DialContext != nil {
+		t.DialContext = config.DialContext
+	}
+	return nil
+}
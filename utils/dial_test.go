@@ -0,0 +1,93 @@
+package utils_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/timeplus-io/go-client/utils"
+)
+
+func TestProxyURL(t *testing.T) {
+	var proxied atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a proxy receives the absolute url of the request
+		if r.URL.Host == "timeplus.internal" {
+			proxied.Add(1)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer proxy.Close()
+
+	config := utils.NewDefaultHTTPClientConfig()
+	config.ProxyURL = proxy.URL
+
+	status, body, err := utils.HttpRequest(http.MethodGet, "http://timeplus.internal/api", nil, utils.NewHttpClient(*config))
+	if err != nil || status != http.StatusOK || string(body) != "ok" {
+		t.Fatalf("request failed: %d %s %v", status, body, err)
+	}
+	res, err := utils.SSEHttpRequestWithHeader(http.MethodPost, "http://timeplus.internal/api", nil, utils.NewStreamingHttpClient(*config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if proxied.Load() != 2 {
+		t.Errorf("expected both requests through the proxy, got %d", proxied.Load())
+	}
+}
+
+func TestInvalidProxyURL(t *testing.T) {
+	config := utils.NewDefaultHTTPClientConfig()
+	config.ProxyURL = "proxy:3128"
+	if err := config.Validate(); err == nil {
+		t.Errorf("expected proxy url without scheme to be rejected")
+	}
+}
+
+func TestDialContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var dialed atomic.Int32
+	config := utils.NewDefaultHTTPClientConfig()
+	config.DialContext = func(ctx context.Context, network string, address string) (net.Conn, error) {
+		dialed.Add(1)
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, network, server.Listener.Addr().String())
+	}
+
+	status, _, err := utils.HttpRequest(http.MethodGet, "http://timeplus.internal/api", nil, utils.NewHttpClient(*config))
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("request failed: %d %v", status, err)
+	}
+	res, err := utils.SSEHttpRequestWithHeader(http.MethodPost, "http://timeplus.internal/api", nil, utils.NewStreamingHttpClient(*config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if dialed.Load() != 2 {
+		t.Errorf("expected both clients to use the dialer, got %d dials", dialed.Load())
+	}
+}
+
+func TestResolveAddress(t *testing.T) {
+	config := utils.NewDefaultHTTPClientConfig()
+	config.ProxyURL = "http://proxy:3128"
+
+	address, resolved := utils.ResolveAddress("https://timeplus.internal:8000", *config)
+	if address != "https://timeplus.internal:8000" || resolved.DialContext != nil || resolved.ProxyURL != config.ProxyURL {
+		t.Errorf("http address should be unchanged, got %s", address)
+	}
+
+	address, resolved = utils.ResolveAddress("unix:///var/run/proton.sock", *config)
+	if address != "http://unix" || resolved.DialContext == nil || resolved.ProxyURL != "" || !resolved.DisableEnvironmentProxy {
+		t.Errorf("unexpected unix address %s %+v", address, resolved)
+	}
+}
@@ -0,0 +1,331 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EndpointPoolAddress is the address of the clients of an EndpointPool, its requests are sent to the endpoints of the pool
+const EndpointPoolAddress = "http://timeplus-endpoints"
+
+const (
+	DefaultHealthCheckInterval = 5 * time.Second
+	DefaultHealthCheckTimeout  = 2 * time.Second
+)
+
+// SelectionPolicy decides which healthy endpoint serves a request
+type SelectionPolicy int
+
+const (
+	// RoundRobin sends the requests to the endpoints in turn
+	RoundRobin SelectionPolicy = iota
+	// LeastLoaded sends a request to the endpoint with the fewest requests in flight,
+	// a streaming query is in flight until its body is closed
+	LeastLoaded
+)
+
+type EndpointPoolConfig struct {
+	// Endpoints are the addresses of the servers, such as https://proton-1:8000
+	Endpoints []string
+	Policy    SelectionPolicy
+	// HealthCheckPath is requested on every endpoint, an endpoint answering with a status below 500 is healthy.
+	// The root path is used if empty.
+	HealthCheckPath string
+	// HealthCheckInterval defaults to DefaultHealthCheckInterval, a negative interval disables the health checks
+	// and an endpoint failing to connect is only tried again when no other endpoint is healthy
+	HealthCheckInterval time.Duration
+	HealthCheckTimeout  time.Duration
+}
+
+// EndpointStatus is the state of an endpoint of the pool
+type EndpointStatus struct {
+	Address  string
+	Healthy  bool
+	InFlight int64
+}
+
+// EndpointPool spreads the requests of the clients over several servers. Requests failing to connect are
+// retried on the next healthy endpoint. Each resumable query sticks to one endpoint as long as it is healthy,
+// so its reconnects keep reading from the same server, see WithEndpointAffinity.
+// Set it as HTTPClientConfig.Endpoints and use EndpointPoolAddress as the address of the clients.
+type EndpointPool struct {
+	config    EndpointPoolConfig
+	endpoints []*endpoint
+	checker   *http.Client
+	next      atomic.Uint64
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type endpoint struct {
+	address  string
+	url      *url.URL
+	healthy  atomic.Bool
+	inFlight atomic.Int64
+}
+
+// NewEndpointPool creates a pool of config.Endpoints and starts the health checks, Close stops them.
+// The health checks are sent with the tls, proxy and authentication of httpConfig.
+func NewEndpointPool(config EndpointPoolConfig, httpConfig HTTPClientConfig) (*EndpointPool, error) {
+	if len(config.Endpoints) == 0 {
+		return nil, fmt.Errorf("endpoint pool requires at least one endpoint")
+	}
+	if config.HealthCheckInterval == 0 {
+		config.HealthCheckInterval = DefaultHealthCheckInterval
+	}
+	if config.HealthCheckTimeout <= 0 {
+		config.HealthCheckTimeout = DefaultHealthCheckTimeout
+	}
+
+	p := &EndpointPool{config: config}
+	for _, address := range config.Endpoints {
+		u, err := url.Parse(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint %s: %w", address, err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			return nil, fmt.Errorf("invalid endpoint %s, should be like http://host:port", address)
+		}
+		e := &endpoint{address: address, url: u}
+		e.healthy.Store(true)
+		p.endpoints = append(p.endpoints, e)
+	}
+
+	httpConfig.Endpoints = nil
+	httpConfig.Timeout = config.HealthCheckTimeout
+	p.checker = NewHttpClient(httpConfig)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+	if config.HealthCheckInterval > 0 {
+		go p.runHealthChecks(ctx)
+	} else {
+		close(p.done)
+	}
+	return p, nil
+}
+
+// Close stops the health checks, the clients of the pool keep working with the last known health
+func (p *EndpointPool) Close() {
+	p.cancel()
+	<-p.done
+}
+
+// Endpoints returns the state of the endpoints in the order they were configured
+func (p *EndpointPool) Endpoints() []EndpointStatus {
+	status := make([]EndpointStatus, len(p.endpoints))
+	for index, e := range p.endpoints {
+		status[index] = EndpointStatus{
+			Address:  e.address,
+			Healthy:  e.healthy.Load(),
+			InFlight: e.inFlight.Load(),
+		}
+	}
+	return status
+}
+
+// CheckHealth checks all endpoints once, such as before using the pool
+func (p *EndpointPool) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, e := range p.endpoints {
+		wg.Add(1)
+		go func(e *endpoint) {
+			defer wg.Done()
+			e.healthy.Store(p.check(ctx, e))
+		}(e)
+	}
+	wg.Wait()
+}
+
+func (p *EndpointPool) runHealthChecks(ctx context.Context) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.config.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		p.CheckHealth(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *EndpointPool) check(ctx context.Context, e *endpoint) bool {
+	checkURL := *e.url
+	checkURL.Path = strings.TrimSuffix(checkURL.Path, "/") + "/" + strings.TrimPrefix(p.config.HealthCheckPath, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, checkURL.String(), nil)
+	if err != nil {
+		return false
+	}
+	res, err := p.checker.Do(req)
+	if err != nil {
+		return false
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	return res.StatusCode < http.StatusInternalServerError
+}
+
+// pick selects an endpoint which is not in tried, preferring the healthy ones
+func (p *EndpointPool) pick(tried map[*endpoint]bool) *endpoint {
+	candidates := make([]*endpoint, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		if !tried[e] && e.healthy.Load() {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		// no healthy endpoint is left, try the others anyway as they may have recovered
+		for _, e := range p.endpoints {
+			if !tried[e] {
+				candidates = append(candidates, e)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	start := int((p.next.Add(1) - 1) % uint64(len(candidates)))
+	if p.config.Policy != LeastLoaded {
+		return candidates[start]
+	}
+	selected := candidates[start]
+	for i := 1; i < len(candidates); i++ {
+		e := candidates[(start+i)%len(candidates)]
+		if e.inFlight.Load() < selected.inFlight.Load() {
+			selected = e
+		}
+	}
+	return selected
+}
+
+type affinityKey struct{}
+
+// affinity remembers the endpoint of the requests sharing a context
+type affinity struct {
+	lock     sync.Mutex
+	endpoint *endpoint
+}
+
+// WithEndpointAffinity returns a context whose requests stick to the endpoint serving the first of them
+// as long as it is healthy, such as the reconnects of a resumable query. The requests of other contexts
+// are spread with the policy of the pool.
+func WithEndpointAffinity(ctx context.Context) context.Context {
+	return context.WithValue(ctx, affinityKey{}, &affinity{})
+}
+
+// pickFor returns the endpoint of the affinity of ctx while it is healthy, or selects one with the policy
+func (p *EndpointPool) pickFor(ctx context.Context, tried map[*endpoint]bool) *endpoint {
+	a, ok := ctx.Value(affinityKey{}).(*affinity)
+	if !ok {
+		return p.pick(tried)
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.endpoint != nil && !tried[a.endpoint] && a.endpoint.healthy.Load() {
+		return a.endpoint
+	}
+	e := p.pick(tried)
+	if e != nil {
+		a.endpoint = e
+	}
+	return e
+}
+
+// roundTripper sends the requests of next to the endpoints of the pool, a nil pool returns next.
+// A request is only sent to another endpoint if no connection could be established, once connected
+// the request may have reached the server and sending it again could duplicate ingested rows.
+func (p *EndpointPool) roundTripper(next http.RoundTripper) http.RoundTripper {
+	if p == nil {
+		return next
+	}
+
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		tried := make(map[*endpoint]bool)
+		var lastErr error
+		for attempt := 0; ; attempt++ {
+			e := p.pickFor(req.Context(), tried)
+			if e == nil {
+				return nil, fmt.Errorf("all endpoints failed: %w", lastErr)
+			}
+			tried[e] = true
+
+			outreq, err := rewriteRequest(req, e, attempt)
+			if err != nil {
+				return nil, err
+			}
+			var connected atomic.Bool
+			outreq = outreq.WithContext(httptrace.WithClientTrace(outreq.Context(), &httptrace.ClientTrace{
+				GotConn: func(httptrace.GotConnInfo) {
+					connected.Store(true)
+				},
+			}))
+
+			e.inFlight.Add(1)
+			res, err := next.RoundTrip(outreq)
+			if err == nil {
+				res.Body = &inFlightBody{ReadCloser: res.Body, endpoint: e}
+				return res, nil
+			}
+			e.inFlight.Add(-1)
+
+			if req.Context().Err() != nil || connected.Load() {
+				return nil, err
+			}
+			e.healthy.Store(false)
+			lastErr = fmt.Errorf("endpoint %s: %w", e.address, err)
+			if req.Body != nil && req.GetBody == nil {
+				// the body has been consumed and cannot be sent to another endpoint
+				return nil, lastErr
+			}
+		}
+	})
+}
+
+// rewriteRequest sends req to e, the body is read again for every attempt after the first one
+func rewriteRequest(req *http.Request, e *endpoint, attempt int) (*http.Request, error) {
+	outreq := req.Clone(req.Context())
+	outreq.URL.Scheme = e.url.Scheme
+	outreq.URL.Host = e.url.Host
+	outreq.URL.Path = strings.TrimSuffix(e.url.Path, "/") + req.URL.Path
+	outreq.URL.RawPath = ""
+	outreq.Host = ""
+
+	if attempt > 0 && req.Body != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body again: %w", err)
+		}
+		outreq.Body = body
+	}
+	return outreq, nil
+}
+
+// inFlightBody counts the request as in flight until its body is closed
+type inFlightBody struct {
+	io.ReadCloser
+	endpoint *endpoint
+	once     sync.Once
+}
+
+func (b *inFlightBody) Close() error {
+	b.once.Do(func() {
+		b.endpoint.inFlight.Add(-1)
+	})
+	return b.ReadCloser.Close()
+}
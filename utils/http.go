@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,10 +12,26 @@ import (
 
 type HTTPClientConfig struct {
 	InsecureSkipVerify  bool
+	TLS                 TLSConfig
 	MaxIdleConns        int
 	MaxConnsPerHost     int
 	MaxIdleConnsPerHost int
 	Timeout             time.Duration
+	// Interceptors wrap the transport of every request including the streaming queries, see Chain
+	Interceptors []Interceptor
+	// Authenticator adds the credentials to every request after the interceptors,
+	// it replaces the api key of the client
+	Authenticator Authenticator
+	// Streaming configures the transport of the streaming queries, see NewStreamingHttpClient
+	Streaming StreamingConfig
+	// ProxyURL sends the requests through a proxy such as http://proxy:3128, the proxy is otherwise
+	// read from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment unless DisableEnvironmentProxy is set
+	ProxyURL                string
+	DisableEnvironmentProxy bool
+	// DialContext replaces the dialer of both clients, see ResolveAddress for unix domain sockets
+	DialContext DialContextFunc
+	// Endpoints spreads the requests over several servers, see EndpointPool
+	Endpoints *EndpointPool
 }
 
 func NewDefaultHTTPClientConfig() *HTTPClientConfig {
@@ -24,6 +41,7 @@ func NewDefaultHTTPClientConfig() *HTTPClientConfig {
 		MaxConnsPerHost:     100,
 		MaxIdleConnsPerHost: 100,
 		Timeout:             10 * time.Second,
+		Streaming:           NewDefaultStreamingConfig(),
 	}
 }
 
@@ -34,22 +52,31 @@ func NewHTTPClientConfig(insecureSkipVerify bool, maxIdleConns int, maxConnsPerH
 		MaxConnsPerHost:     maxConnsPerHost,
 		MaxIdleConnsPerHost: maxIdleConnsPerHost,
 		Timeout:             time.Duration(timeout) * time.Second,
+		Streaming:           NewDefaultStreamingConfig(),
 	}
 }
 
 func NewHttpClient(config HTTPClientConfig) *http.Client {
 	t := http.DefaultTransport.(*http.Transport).Clone()
-	t.TLSClientConfig.InsecureSkipVerify = config.InsecureSkipVerify
 	t.MaxIdleConns = config.MaxIdleConns
 	t.MaxConnsPerHost = config.MaxConnsPerHost
 	t.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
 
 	return &http.Client{
 		Timeout:   config.Timeout,
-		Transport: t,
+		Transport: config.wrapTransport(config.Endpoints.roundTripper(config.configureTransport(t))),
 	}
 }
 
+// wrapTransport applies the interceptors and the authenticator of config to t
+func (config HTTPClientConfig) wrapTransport(t http.RoundTripper) http.RoundTripper {
+	interceptors := config.Interceptors
+	if config.Authenticator != nil {
+		interceptors = append(interceptors[:len(interceptors):len(interceptors)], AuthInterceptor(config.Authenticator))
+	}
+	return Chain(t, interceptors...)
+}
+
 func NewDefaultHttpClient() *http.Client {
 	config := NewDefaultHTTPClientConfig()
 	return NewHttpClient(*config)
@@ -67,14 +94,22 @@ func HttpRequestWithToken(method string, url string, payload interface{}, client
 }
 
 func HttpRequestWithAPIKey(method string, url string, payload interface{}, client *http.Client, key string) (int, []byte, error) {
+	// an empty key leaves the authentication to the authenticator of the client
 	headers := make(map[string]string)
-	headers["X-Api-key"] = key
+	if len(key) > 0 {
+		headers["X-Api-key"] = key
+	}
 
 	return HttpRequestWithHeader(method, url, payload, client, headers)
 }
 
 // request will propragate error if the response code is not 2XX
 func HttpRequestWithHeader(method string, url string, payload interface{}, client *http.Client, headers map[string]string) (int, []byte, error) {
+	return HttpRequestWithContext(context.Background(), method, url, payload, client, headers)
+}
+
+// HttpRequestWithContext sends the request with ctx, which cancels it and carries the trace of the caller
+func HttpRequestWithContext(ctx context.Context, method string, url string, payload interface{}, client *http.Client, headers map[string]string) (int, []byte, error) {
 	var body io.Reader
 	if payload == nil {
 		body = nil
@@ -83,7 +118,7 @@ func HttpRequestWithHeader(method string, url string, payload interface{}, clien
 		body = bytes.NewBuffer(jsonPostValue)
 	}
 
-	req, err := http.NewRequest(method, url, body)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return 0, nil, err
 	}
@@ -111,25 +146,26 @@ func HttpRequestWithHeader(method string, url string, payload interface{}, clien
 	return res.StatusCode, resBody, nil
 }
 
+// SSEHttpRequestWithAPIKey creates a streaming client for the request,
+// reuse a client of NewStreamingHttpClient with SSEHttpRequestWithHeader to pool the connections
 func SSEHttpRequestWithAPIKey(method string, url string, payload interface{}, config *HTTPClientConfig, key string) (*http.Response, error) {
-	t := http.DefaultTransport.(*http.Transport).Clone()
-	t.TLSClientConfig.InsecureSkipVerify = config.InsecureSkipVerify
-	t.MaxIdleConns = config.MaxIdleConns
-	t.MaxConnsPerHost = config.MaxConnsPerHost
-	t.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
-	t.TLSHandshakeTimeout = 0 * time.Second
-
-	client := &http.Client{
-		Transport: t,
-	}
+	client := NewStreamingHttpClient(*config)
 
+	// an empty key leaves the authentication to the authenticator of the client
 	headers := make(map[string]string)
-	headers["X-Api-key"] = key
+	if len(key) > 0 {
+		headers["X-Api-key"] = key
+	}
 
 	return SSEHttpRequestWithHeader(method, url, payload, client, headers)
 }
 
 func SSEHttpRequestWithHeader(method string, url string, payload interface{}, client *http.Client, headers map[string]string) (*http.Response, error) {
+	return SSEHttpRequestWithContext(context.Background(), method, url, payload, client, headers)
+}
+
+// SSEHttpRequestWithContext sends the request with ctx, which cancels the stream when done
+func SSEHttpRequestWithContext(ctx context.Context, method string, url string, payload interface{}, client *http.Client, headers map[string]string) (*http.Response, error) {
 	var body io.Reader
 	if payload == nil {
 		body = nil
@@ -138,7 +174,7 @@ func SSEHttpRequestWithHeader(method string, url string, payload interface{}, cl
 		body = bytes.NewBuffer(jsonPostValue)
 	}
 
-	req, err := http.NewRequest(method, url, body)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
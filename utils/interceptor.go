@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"net/http"
+	"time"
+)
+
+const DefaultUserAgent = "timeplus-go-client"
+
+// Interceptor wraps the RoundTripper doing the rest of a request, it is used to add headers,
+// logging or metrics to every call of a client including the streaming queries
+type Interceptor func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc turns a function into a http.RoundTripper
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Chain wraps transport with interceptors, the first interceptor sees the request first
+func Chain(transport http.RoundTripper, interceptors ...Interceptor) http.RoundTripper {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		transport = interceptors[i](transport)
+	}
+	return transport
+}
+
+// Logger receives the messages of LoggingInterceptor, *log.Logger satisfies it
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+// HeaderInterceptor sets headers on every request, overriding the headers set by the client
+func HeaderInterceptor(headers map[string]string) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			for key, value := range headers {
+				req.Header.Set(key, value)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// UserAgentInterceptor sets the User-Agent header, DefaultUserAgent is used if userAgent is empty
+func UserAgentInterceptor(userAgent string) Interceptor {
+	if len(userAgent) == 0 {
+		userAgent = DefaultUserAgent
+	}
+	return HeaderInterceptor(map[string]string{"User-Agent": userAgent})
+}
+
+// LoggingInterceptor logs the method, url, status and duration of every request. For streaming
+// queries the duration is the time until the response header, the headers are never logged
+// since they carry the api key.
+func LoggingInterceptor(logger Logger) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			res, err := next.RoundTrip(req)
+			if err != nil {
+				logger.Printf("%s %s failed after %s: %s", req.Method, req.URL.Redacted(), time.Since(start), err)
+				return res, err
+			}
+			logger.Printf("%s %s %d %s", req.Method, req.URL.Redacted(), res.StatusCode, time.Since(start))
+			return res, nil
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package utils_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/timeplus-io/go-client/timeplus"
+	"github.com/timeplus-io/go-client/timeplustest"
+	"github.com/timeplus-io/go-client/utils"
+)
+
+type recordingLogger struct {
+	lock  sync.Mutex
+	lines []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...any) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func TestInterceptors(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	server.ScriptQuery("select", timeplustest.QueryScript{
+		Events: []timeplustest.ScriptEvent{timeplustest.Rows([]any{1})},
+	})
+
+	order := make([]string, 0)
+	tracing := func(name string) utils.Interceptor {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return utils.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+
+	logger := &recordingLogger{}
+	config := utils.NewDefaultHTTPClientConfig()
+	config.Interceptors = []utils.Interceptor{
+		tracing("outer"),
+		utils.UserAgentInterceptor("test-agent/1.0"),
+		utils.HeaderInterceptor(map[string]string{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}),
+		utils.LoggingInterceptor(logger),
+		tracing("inner"),
+	}
+	client := timeplus.NewCientWithHttpConfig(server.URL, "", "key", config)
+
+	if _, err := client.ListStream(); err != nil {
+		t.Fatal(err)
+	}
+	result, err := client.QueryStream("select 1", 10, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result.Cancel()
+	if err := timeplus.NewLowLevelCientWithHttpConfig(server.URL, config).InsertData(&timeplus.IngestPayload{Stream: "missing"}); err == nil {
+		t.Errorf("expected error ingesting into a missing stream")
+	}
+
+	if strings.Join(order, ",") != "outer,inner,outer,inner,outer,inner" {
+		t.Errorf("unexpected interceptor order %v", order)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+	for _, req := range requests {
+		if req.Header.Get("User-Agent") != "test-agent/1.0" || req.Header.Get("traceparent") == "" {
+			t.Errorf("interceptor headers missing from %s %s: %v", req.Method, req.Path, req.Header)
+		}
+	}
+	// the streaming query keeps its own headers
+	if requests[1].Header.Get("Accept") != "text/event-stream" {
+		t.Errorf("unexpected headers of streaming query %v", requests[1].Header)
+	}
+
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+	if len(logger.lines) != 3 || !strings.HasPrefix(logger.lines[0], "GET "+server.URL+"/api/") || !strings.Contains(logger.lines[2], " 404 ") {
+		t.Errorf("unexpected log lines %v", logger.lines)
+	}
+	for _, line := range logger.lines {
+		if strings.Contains(line, "key") {
+			t.Errorf("api key logged in %s", line)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// StreamingConfig controls the connections of the streaming queries, which stay open as long as
+// the query runs, so they use a transport separate from the short requests of the REST calls.
+// Zero values leave the setting unlimited.
+type StreamingConfig struct {
+	MaxIdleConns        int
+	MaxConnsPerHost     int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	// KeepAlive is the interval of the tcp keep alive probes of the query connections
+	KeepAlive time.Duration
+	// ResponseHeaderTimeout limits the wait for the server to accept the query
+	ResponseHeaderTimeout time.Duration
+	// ReadTimeout ends a query when no data is received within it, it should be longer than
+	// the batching time of the queries
+	ReadTimeout time.Duration
+}
+
+func NewDefaultStreamingConfig() StreamingConfig {
+	return StreamingConfig{
+		MaxIdleConns:        10,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+		KeepAlive:           30 * time.Second,
+	}
+}
+
+// ErrStreamReadTimeout is returned by the body of a streaming response which received no data within the read timeout
+var ErrStreamReadTimeout = fmt.Errorf("streaming read timeout")
+
+// NewStreamingHttpClient returns the client for the streaming queries, the client has no overall
+// timeout and should be shared by all queries so the connections are pooled
+func NewStreamingHttpClient(config HTTPClientConfig) *http.Client {
+	streaming := config.Streaming
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: streaming.KeepAlive,
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = dialer.DialContext
+	t.MaxIdleConns = streaming.MaxIdleConns
+	t.MaxConnsPerHost = streaming.MaxConnsPerHost
+	t.MaxIdleConnsPerHost = streaming.MaxIdleConnsPerHost
+	t.IdleConnTimeout = streaming.IdleConnTimeout
+	t.ResponseHeaderTimeout = streaming.ResponseHeaderTimeout
+	// compression would buffer the events of the stream
+	t.DisableCompression = true
+
+	transport := config.Endpoints.roundTripper(config.configureTransport(t))
+	if streaming.ReadTimeout > 0 {
+		transport = readTimeoutTransport(transport, streaming.ReadTimeout)
+	}
+
+	return &http.Client{
+		Transport: config.wrapTransport(transport),
+	}
+}
+
+func readTimeoutTransport(next http.RoundTripper, timeout time.Duration) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		res, err := next.RoundTrip(req)
+		if err != nil {
+			return res, err
+		}
+		res.Body = newReadTimeoutBody(res.Body, timeout)
+		return res, nil
+	})
+}
+
+// readTimeoutBody closes the body when no read completes within the timeout, the blocked read then fails with ErrStreamReadTimeout
+type readTimeoutBody struct {
+	body    io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+
+	lock     sync.Mutex
+	timedOut bool
+}
+
+func newReadTimeoutBody(body io.ReadCloser, timeout time.Duration) *readTimeoutBody {
+	b := &readTimeoutBody{body: body, timeout: timeout}
+	b.timer = time.AfterFunc(timeout, func() {
+		b.lock.Lock()
+		b.timedOut = true
+		b.lock.Unlock()
+		body.Close()
+	})
+	return b
+}
+
+func (b *readTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	if err != nil {
+		b.lock.Lock()
+		timedOut := b.timedOut
+		b.lock.Unlock()
+		if timedOut {
+			return n, ErrStreamReadTimeout
+		}
+	}
+	return n, err
+}
+
+func (b *readTimeoutBody) Close() error {
+	b.timer.Stop()
+	return b.body.Close()
+}
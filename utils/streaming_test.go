@@ -0,0 +1,107 @@
+package utils_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/timeplus-io/go-client/timeplus"
+	"github.com/timeplus-io/go-client/timeplustest"
+	"github.com/timeplus-io/go-client/utils"
+)
+
+func TestStreamingReadTimeout(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	server.ScriptQuery("select", timeplustest.QueryScript{
+		Events:   []timeplustest.ScriptEvent{timeplustest.Rows([]any{1})},
+		KeepOpen: true,
+	})
+
+	config := utils.NewDefaultHTTPClientConfig()
+	config.Streaming.ReadTimeout = 100 * time.Millisecond
+	client := timeplus.NewCientWithHttpConfig(server.URL, "", "", config)
+
+	result, err := client.QueryStream("select 1", 10, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer result.Cancel()
+
+	items := result.ResultStream.Observe()
+	if item := <-items; item.E != nil {
+		t.Fatalf("unexpected error %s", item.E)
+	}
+	select {
+	case item := <-items:
+		if !errors.Is(item.E, utils.ErrStreamReadTimeout) {
+			t.Errorf("expected read timeout, got %v", item)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("idle query not ended by the read timeout")
+	}
+}
+
+func TestStreamingResponseHeaderTimeout(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	server.InjectFault(timeplustest.Fault{Path: "/queries", Delay: time.Second})
+
+	config := utils.NewDefaultHTTPClientConfig()
+	config.Streaming.ResponseHeaderTimeout = 50 * time.Millisecond
+	client := timeplus.NewCientWithHttpConfig(server.URL, "", "", config)
+
+	start := time.Now()
+	if _, err := client.QueryStream("select 1", 10, 100); err == nil {
+		t.Errorf("expected response header timeout")
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("response header timeout not applied")
+	}
+}
+
+func TestStreamingTransportIsShared(t *testing.T) {
+	server := timeplustest.NewServer()
+	defer server.Close()
+	server.ScriptQuery("select", timeplustest.QueryScript{KeepOpen: true})
+
+	config := utils.NewDefaultHTTPClientConfig()
+	config.Streaming.MaxConnsPerHost = 1
+	client := timeplus.NewCientWithHttpConfig(server.URL, "", "", config)
+
+	first, err := client.QueryStream("select 1", 10, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the second query waits for the connection held by the first one
+	started := make(chan *timeplus.QueryResultStream)
+	go func() {
+		second, err := client.QueryStream("select 2", 10, 100)
+		if err != nil {
+			t.Error(err)
+		}
+		started <- second
+	}()
+
+	select {
+	case <-started:
+		t.Fatalf("connection limit of the streaming transport not shared")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// the rest calls use their own transport
+	if _, err := client.ListStream(); err != nil {
+		t.Fatal(err)
+	}
+
+	first.Cancel()
+	select {
+	case second := <-started:
+		if second != nil {
+			second.Cancel()
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("second query not started after the first ended")
+	}
+}
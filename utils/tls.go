@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLSConfig configures the tls of the regular and the streaming clients, the zero value uses the system roots.
+// The server is verified when a CA is configured, otherwise only when InsecureSkipVerify of the HTTPClientConfig is false.
+type TLSConfig struct {
+	// CAFile and CAPEM add certificate authorities to verify the server, the system roots are used if both are empty
+	CAFile string
+	CAPEM  []byte
+	// CertFile and KeyFile are the client certificate for mutual tls, they are reloaded when the files
+	// change so rotated certificates are picked up by the new connections
+	CertFile string
+	KeyFile  string
+	// CertPEM and KeyPEM are a client certificate which does not change
+	CertPEM []byte
+	KeyPEM  []byte
+	// MinVersion defaults to tls.VersionTLS12
+	MinVersion uint16
+	// ServerName overrides the name verified against the server certificate
+	ServerName string
+}
+
+func (c TLSConfig) build(insecureSkipVerify bool) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: insecureSkipVerify,
+		MinVersion:         c.MinVersion,
+		ServerName:         c.ServerName,
+	}
+	if tlsConfig.MinVersion == 0 {
+		tlsConfig.MinVersion = tls.VersionTLS12
+	}
+
+	if len(c.CAFile) > 0 || len(c.CAPEM) > 0 {
+		pool := x509.NewCertPool()
+		if len(c.CAFile) > 0 {
+			data, err := os.ReadFile(c.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read ca file: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(data) {
+				return nil, fmt.Errorf("no certificate found in ca file %s", c.CAFile)
+			}
+		}
+		if len(c.CAPEM) > 0 && !pool.AppendCertsFromPEM(c.CAPEM) {
+			return nil, fmt.Errorf("no certificate found in ca pem")
+		}
+		tlsConfig.RootCAs = pool
+		// a configured CA is meant to verify the server, even with the insecure default config
+		tlsConfig.InsecureSkipVerify = false
+	}
+
+	switch {
+	case len(c.CertFile) > 0 || len(c.KeyFile) > 0:
+		reloader, err := newCertReloader(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.GetClientCertificate = reloader.getClientCertificate
+	case len(c.CertPEM) > 0 || len(c.KeyPEM) > 0:
+		cert, err := tls.X509KeyPair(c.CertPEM, c.KeyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// certReloader loads the client certificate again when its files are modified
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	lock     sync.Mutex
+	cert     *tls.Certificate
+	certTime time.Time
+	keyTime  time.Time
+}
+
+func newCertReloader(certFile string, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) reload() error {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return fmt.Errorf("failed to read client certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read client key: %w", err)
+	}
+	if r.cert != nil && certInfo.ModTime().Equal(r.certTime) && keyInfo.ModTime().Equal(r.keyTime) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("invalid client certificate: %w", err)
+	}
+	r.cert = &cert
+	r.certTime = certInfo.ModTime()
+	r.keyTime = keyInfo.ModTime()
+	return nil
+}
+
+// getClientCertificate keeps using the loaded certificate if the files cannot be reloaded,
+// such as while they are being replaced
+func (r *certReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.reload()
+	return r.cert, nil
+}
@@ -0,0 +1,289 @@
+package utils_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timeplus-io/go-client/utils"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCert(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func newTestCA(t *testing.T) *testCert {
+	return newTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "test ca"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil)
+}
+
+func newClientCert(t *testing.T, ca *testCert, name string) *testCert {
+	return newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca)
+}
+
+// tlsServer requires a client certificate and records the name of the clients which connected
+type tlsServer struct {
+	*httptest.Server
+
+	lock    sync.Mutex
+	clients []string
+}
+
+func newTLSServer(t *testing.T, ca *testCert, serverName string) *tlsServer {
+	serverCert := newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: serverName},
+		DNSNames:    []string{serverName},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+	pair, err := tls.X509KeyPair(serverCert.certPEM, serverCert.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	s := &tlsServer{}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		s.clients = append(s.clients, r.TLS.PeerCertificates[0].Subject.CommonName)
+		s.lock.Unlock()
+		w.Write([]byte("ok"))
+	}))
+	s.TLS = &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}
+	s.StartTLS()
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *tlsServer) lastClient() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.clients) == 0 {
+		return ""
+	}
+	return s.clients[len(s.clients)-1]
+}
+
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTLSClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	server := newTLSServer(t, ca, "timeplus.internal")
+	client := newClientCert(t, ca, "client")
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	writeFile(t, caFile, ca.certPEM, time.Now())
+
+	config := utils.NewDefaultHTTPClientConfig()
+	config.TLS = utils.TLSConfig{
+		CAFile:     caFile,
+		CertPEM:    client.certPEM,
+		KeyPEM:     client.keyPEM,
+		ServerName: "timeplus.internal",
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	status, body, err := utils.HttpRequest(http.MethodGet, server.URL, nil, utils.NewHttpClient(*config))
+	if err != nil || status != http.StatusOK || string(body) != "ok" {
+		t.Fatalf("request failed: %d %s %v", status, body, err)
+	}
+
+	res, err := utils.SSEHttpRequestWithHeader(http.MethodPost, server.URL, nil, utils.NewStreamingHttpClient(*config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	if server.lastClient() != "client" {
+		t.Errorf("unexpected client %s", server.lastClient())
+	}
+}
+
+func TestTLSClientCertificateReload(t *testing.T) {
+	ca := newTestCA(t)
+	server := newTLSServer(t, ca, "timeplus.internal")
+	first := newClientCert(t, ca, "first")
+	second := newClientCert(t, ca, "second")
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+	now := time.Now()
+	writeFile(t, certFile, first.certPEM, now)
+	writeFile(t, keyFile, first.keyPEM, now)
+
+	config := utils.NewDefaultHTTPClientConfig()
+	config.TLS = utils.TLSConfig{
+		CAPEM:      ca.certPEM,
+		CertFile:   certFile,
+		KeyFile:    keyFile,
+		ServerName: "timeplus.internal",
+	}
+	client := utils.NewHttpClient(*config)
+
+	if _, _, err := utils.HttpRequest(http.MethodGet, server.URL, nil, client); err != nil {
+		t.Fatal(err)
+	}
+	if server.lastClient() != "first" {
+		t.Fatalf("unexpected client %s", server.lastClient())
+	}
+
+	later := now.Add(time.Minute)
+	writeFile(t, certFile, second.certPEM, later)
+	writeFile(t, keyFile, second.keyPEM, later)
+	server.CloseClientConnections()
+
+	if _, _, err := utils.HttpRequest(http.MethodGet, server.URL, nil, client); err != nil {
+		t.Fatal(err)
+	}
+	if server.lastClient() != "second" {
+		t.Errorf("rotated certificate not used, got %s", server.lastClient())
+	}
+}
+
+func TestTLSVerification(t *testing.T) {
+	ca := newTestCA(t)
+	server := newTLSServer(t, ca, "timeplus.internal")
+	client := newClientCert(t, ca, "client")
+
+	cases := map[string]utils.TLSConfig{
+		"unknown ca": {
+			CertPEM: client.certPEM, KeyPEM: client.keyPEM, ServerName: "timeplus.internal",
+		},
+		"wrong server name": {
+			CAPEM: ca.certPEM, CertPEM: client.certPEM, KeyPEM: client.keyPEM, ServerName: "other.internal",
+		},
+		"no client certificate": {
+			CAPEM: ca.certPEM, ServerName: "timeplus.internal",
+		},
+	}
+	for name, tlsConfig := range cases {
+		config := utils.NewDefaultHTTPClientConfig()
+		config.InsecureSkipVerify = false
+		config.TLS = tlsConfig
+		if _, _, err := utils.HttpRequest(http.MethodGet, server.URL, nil, utils.NewHttpClient(*config)); err == nil {
+			t.Errorf("%s: expected request to fail", name)
+		}
+	}
+}
+
+func TestTLSMinVersion(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	config := utils.NewDefaultHTTPClientConfig()
+	config.InsecureSkipVerify = true
+	config.TLS.MinVersion = tls.VersionTLS13
+	if _, _, err := utils.HttpRequest(http.MethodGet, server.URL, nil, utils.NewHttpClient(*config)); err == nil {
+		t.Errorf("expected tls 1.2 server to be rejected")
+	}
+
+	config.TLS.MinVersion = tls.VersionTLS12
+	if _, _, err := utils.HttpRequest(http.MethodGet, server.URL, nil, utils.NewHttpClient(*config)); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+}
+
+func TestTLSInvalidConfig(t *testing.T) {
+	config := utils.NewDefaultHTTPClientConfig()
+	config.TLS.CAFile = filepath.Join(t.TempDir(), "missing.pem")
+	if err := config.Validate(); err == nil {
+		t.Fatal("expected missing ca file to be reported")
+	}
+
+	client := utils.NewHttpClient(*config)
+	if _, _, err := utils.HttpRequest(http.MethodGet, "https://"+net.JoinHostPort("127.0.0.1", "1"), nil, client); err == nil {
+		t.Errorf("expected request with invalid tls config to fail")
+	}
+}
+
+func TestTLSCAEnablesVerification(t *testing.T) {
+	server := newTLSServer(t, newTestCA(t), "timeplus.internal")
+	other := newTestCA(t)
+	client := newClientCert(t, other, "client")
+
+	// the default config skips the verification unless a CA is configured
+	config := utils.NewDefaultHTTPClientConfig()
+	config.TLS = utils.TLSConfig{CAPEM: other.certPEM, CertPEM: client.certPEM, KeyPEM: client.keyPEM, ServerName: "timeplus.internal"}
+	if _, _, err := utils.HttpRequest(http.MethodGet, server.URL, nil, utils.NewHttpClient(*config)); err == nil {
+		t.Errorf("expected the server certificate of another CA to be rejected")
+	}
+}